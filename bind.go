@@ -0,0 +1,111 @@
+package jsont
+
+import (
+	"context"
+	"errors"
+)
+
+// BoundTemplate is a template bound with args (see Template.Bind, NamedTemplate.Bind and MixedTemplate.Bind)
+//
+// A bound template can be used as an arg value for another template - the bound template is rendered directly
+// into the output of the other template (rather than being rendered separately and then copied)
+//
+// Errors from rendering a bound template are wrapped (by an ArgMarshalError) with the position of the arg
+// marker in the other template - so that the error indicates the nesting path, e.g.
+//   named arg 'address' at line 3, column 14: named arg 'city' at line 1, column 9: expected named arg 'city'
+//
+// A bound template that is (directly or indirectly) bound into itself fails to render with an error
+type BoundTemplate struct {
+	render  func(ctx context.Context) ([]byte, error)
+	writeTo func(ctx context.Context, w renderWriter) error
+}
+
+// JSONTData implements DataProvider
+func (b *BoundTemplate) JSONTData() ([]byte, error) {
+	ctx, err := b.enter(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return b.render(ctx)
+}
+
+func (b *BoundTemplate) writeData(ctx context.Context, w renderWriter) error {
+	ctx, err := b.enter(ctx)
+	if err != nil {
+		return err
+	}
+	if b.writeTo == nil {
+		// the bound template validates its own output - so must be rendered separately...
+		var data []byte
+		if data, err = b.render(ctx); err == nil {
+			w.Write(data)
+		}
+		return err
+	}
+	return b.writeTo(ctx, w)
+}
+
+// boundTemplatesKey is the context key for the bound templates currently being rendered
+type boundTemplatesKey struct{}
+
+type boundTemplates struct {
+	bound  *BoundTemplate
+	parent *boundTemplates
+}
+
+// enter returns a context that records the bound template as being rendered - an error is returned if the bound
+// template is already being rendered (i.e. the bound template is, directly or indirectly, bound into itself)
+func (b *BoundTemplate) enter(ctx context.Context) (context.Context, error) {
+	rendering, _ := ctx.Value(boundTemplatesKey{}).(*boundTemplates)
+	for r := rendering; r != nil; r = r.parent {
+		if r.bound == b {
+			return nil, errors.New("bound template is bound into itself")
+		}
+	}
+	return context.WithValue(ctx, boundTemplatesKey{}, &boundTemplates{bound: b, parent: rendering}), nil
+}
+
+// Bind binds the template with the specified args (see Template.Bind)
+func (t *jsonTemplate) Bind(args ...interface{}) *BoundTemplate {
+	result := &BoundTemplate{
+		render: func(ctx context.Context) ([]byte, error) {
+			return t.RenderContext(ctx, args...)
+		},
+	}
+	if t.schema == nil && t.validation != validateOutput {
+		result.writeTo = func(ctx context.Context, w renderWriter) error {
+			return t.renderTo(ctx, w, args, nil)
+		}
+	}
+	return result
+}
+
+// Bind binds the template with the specified args (see NamedTemplate.Bind)
+func (t *jsonNamedTemplate) Bind(args map[string]interface{}) *BoundTemplate {
+	result := &BoundTemplate{
+		render: func(ctx context.Context) ([]byte, error) {
+			return t.RenderContext(ctx, MapArgs(args))
+		},
+	}
+	if t.schema == nil && t.validation != validateOutput {
+		result.writeTo = func(ctx context.Context, w renderWriter) error {
+			return t.renderTo(ctx, w, MapArgs(args), nil)
+		}
+	}
+	return result
+}
+
+// Bind binds the template with the specified args (see MixedTemplate.Bind)
+func (t *jsonMixedTemplate) Bind(named map[string]interface{}, positional ...interface{}) *BoundTemplate {
+	result := &BoundTemplate{
+		render: func(ctx context.Context) ([]byte, error) {
+			return t.RenderContext(ctx, named, positional...)
+		},
+	}
+	if t.schema == nil && t.validation != validateOutput {
+		result.writeTo = func(ctx context.Context, w renderWriter) error {
+			return t.renderTo(ctx, w, named, positional, nil)
+		}
+	}
+	return result
+}
@@ -0,0 +1,238 @@
+package jsont
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	address := MustCompileNamedTemplate(`{"city":?city,"postcode":?postcode}`)
+	item := MustCompileTemplate(`{"id":?,"qty":?}`)
+	order := MustCompileNamedTemplate(`{"address":?address,"items":[?item1,?item2]}`)
+
+	str, err := order.String(map[string]interface{}{
+		"address": address.Bind(map[string]interface{}{"city": "Hobbiton", "postcode": "SH1"}),
+		"item1":   item.Bind("a", 1),
+		"item2":   item.Bind("b", 2),
+	})
+	require.NoError(t, err)
+	require.Equal(t, `{"address":{"city":"Hobbiton","postcode":"SH1"},"items":[{"id":"a","qty":1},{"id":"b","qty":2}]}`, str)
+
+	// bound templates can be nested...
+	wrapper := MustCompileTemplate(`{"order":?}`)
+	data, err := wrapper.Data(order.Bind(map[string]interface{}{
+		"address": address.Bind(map[string]interface{}{"city": "Bree", "postcode": nil}),
+		"item1":   item.Bind("a", 1),
+		"item2":   nil,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, `{"order":{"address":{"city":"Bree","postcode":null},"items":[{"id":"a","qty":1},null]}}`, string(data))
+
+	// used directly as a DataProvider...
+	data, err = item.Bind("c", 3).JSONTData()
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"c","qty":3}`, string(data))
+}
+
+func TestMixedTemplateBind(t *testing.T) {
+	item := MustCompileMixedTemplate(`{"id":?,"name":?name}`)
+	order := MustCompileMixedTemplate(`{"ref":?ref,"items":[?,?]}`)
+
+	str, err := order.String(map[string]interface{}{"ref": "o1"},
+		item.Bind(map[string]interface{}{"name": "a"}, 1),
+		item.Bind(nil, 2))
+	require.Error(t, err)
+	require.Equal(t, "arg[1] at line 1, column 24: expected named arg 'name'", err.Error())
+
+	str, err = order.String(map[string]interface{}{"ref": "o1"},
+		item.Bind(map[string]interface{}{"name": "a"}, 1),
+		item.DefaultArgValue("name", "b").Bind(nil, 2))
+	require.NoError(t, err)
+	require.Equal(t, `{"ref":"o1","items":[{"id":1,"name":"a"},{"id":2,"name":"b"}]}`, str)
+
+	// used as an arg of other templates and directly as a DataProvider...
+	bound := item.Bind(map[string]interface{}{"name": "c"}, 3)
+	str, err = MustCompileNamedTemplate(`{"item":?item}`).String(map[string]interface{}{"item": bound})
+	require.NoError(t, err)
+	require.Equal(t, `{"item":{"id":3,"name":"c"}}`, str)
+	data, err := bound.JSONTData()
+	require.NoError(t, err)
+	require.Equal(t, `{"id":3,"name":"c"}`, string(data))
+
+	// with output validation (rendered separately)...
+	bound = item.Options(OptionValidateOutput).Bind(map[string]interface{}{"name": "d"}, 4)
+	data, err = MustCompileTemplate(`[?]`).Data(bound)
+	require.NoError(t, err)
+	require.Equal(t, `[{"id":4,"name":"d"}]`, string(data))
+}
+
+func TestBindErrorsNestingPath(t *testing.T) {
+	address := MustCompileNamedTemplate(`{"city":?city}`)
+	item := MustCompileTemplate(`{"id":?}`)
+	order := MustCompileNamedTemplate(`{
+  "address": ?address,
+  "item": ?item
+}`)
+	wrapper := MustCompileTemplate(`{"order":?}`)
+
+	_, err := wrapper.String(order.Bind(map[string]interface{}{
+		"address": address.Bind(nil),
+		"item":    item.Bind("a"),
+	}))
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrMissingArg))
+	require.Equal(t, "arg[0] at line 1, column 10: named arg 'address' at line 2, column 14: expected named arg 'city'", err.Error())
+
+	_, err = wrapper.Data(order.Bind(map[string]interface{}{
+		"address": address.Bind(map[string]interface{}{"city": "Bree"}),
+		"item":    item.Bind(func() {}),
+	}))
+	require.Error(t, err)
+	require.Equal(t, "arg[0] at line 1, column 10: named arg 'item' at line 3, column 11: arg[0] at line 1, column 7: json: unsupported type: func()", err.Error())
+
+	_, err = wrapper.Data(item.Bind())
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrArgCount))
+
+	// errors from bound templates are collected...
+	collecting := MustCompileNamedTemplate(`[?a,?b]`, OptionCollectErrors)
+	_, err = collecting.String(map[string]interface{}{"a": item.Bind(), "b": address.Bind(nil)})
+	require.Error(t, err)
+	var errs *ArgErrors
+	require.True(t, errors.As(err, &errs))
+	require.Equal(t, 2, len(errs.Errors))
+}
+
+func TestBindWithFiltersAndValidation(t *testing.T) {
+	inner := MustCompileTemplate(`{"a":?}`)
+	// filters receive the bound template (rather than it being written directly)...
+	outer := MustCompileNamedTemplate(`{"inner":?inner|wrap}`, OptionFilters(map[string]FilterFunc{
+		"wrap": func(value interface{}, args ...string) (interface{}, error) {
+			data, err := value.(*BoundTemplate).JSONTData()
+			return []interface{}{json.RawMessage(data)}, err
+		},
+	}))
+	str, err := outer.String(map[string]interface{}{"inner": inner.Bind(1)})
+	require.NoError(t, err)
+	require.Equal(t, `{"inner":[{"a":1}]}`, str)
+
+	validated := MustCompileTemplate(`{"a":?}`, OptionSchema([]byte(`{"properties":{"a":{"type":"integer"}}}`)))
+	outer = MustCompileNamedTemplate(`{"inner":?inner}`)
+	str, err = outer.String(map[string]interface{}{"inner": validated.Bind(1)})
+	require.NoError(t, err)
+	require.Equal(t, `{"inner":{"a":1}}`, str)
+	_, err = outer.String(map[string]interface{}{"inner": validated.Bind("x")})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrSchemaValidation))
+	require.Equal(t, "named arg 'inner' at line 1, column 10: output does not match schema at '#/a': expected type integer but got string", err.Error())
+
+	// NewWith resolves bound templates into the new template...
+	jt, err := MustCompileTemplate(`[?,?]`).NewWith(inner.Bind(2))
+	require.NoError(t, err)
+	str, err = jt.String(3)
+	require.NoError(t, err)
+	require.Equal(t, `[{"a":2},3]`, str)
+	_, err = MustCompileTemplate(`[?,?]`).NewWith(inner.Bind())
+	require.Error(t, err)
+}
+
+func TestBindContext(t *testing.T) {
+	inner := MustCompileTemplate(`{"a":?}`)
+	outer := MustCompileNamedTemplate(`{"inner":?inner}`)
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "from context")
+	data, err := outer.RenderContext(ctx, MapArgs{"inner": inner.Bind(func(ctx context.Context) (interface{}, error) {
+		return ctx.Value(ctxKey{}), nil
+	})})
+	require.NoError(t, err)
+	require.Equal(t, `{"inner":{"a":"from context"}}`, string(data))
+
+	cctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = outer.RenderContext(cctx, MapArgs{"inner": inner.Bind(1)})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestBindCycle(t *testing.T) {
+	jt := MustCompileNamedTemplate(`{"self":?self}`)
+	args := map[string]interface{}{}
+	bound := jt.Bind(args)
+	args["self"] = bound
+	_, err := bound.JSONTData()
+	require.Error(t, err)
+	require.Equal(t, "named arg 'self' at line 1, column 9: bound template is bound into itself", err.Error())
+	_, err = MustCompileTemplate(`[?]`).String(bound)
+	require.Error(t, err)
+	require.Equal(t, "arg[0] at line 1, column 2: named arg 'self' at line 1, column 9: bound template is bound into itself", err.Error())
+
+	// indirectly bound into itself...
+	other := MustCompileTemplate(`[?]`)
+	otherArgs := []interface{}{nil}
+	otherBound := other.Bind(otherArgs...)
+	args["self"] = otherBound
+	otherArgs[0] = bound
+	_, err = MustCompileTemplate(`[?]`).String(bound)
+	require.Error(t, err)
+
+	// the same bound template can be used more than once (when not nested within itself)...
+	args["self"] = 1
+	str, err := MustCompileTemplate(`[?1,?1,?2]`).String(bound, bound)
+	require.NoError(t, err)
+	require.Equal(t, `[{"self":1},{"self":1},{"self":1}]`, str)
+}
+
+func TestBindCycleWrapped(t *testing.T) {
+	jt := MustCompileNamedTemplate(`{?x}`)
+	args := map[string]interface{}{}
+	bound := jt.Bind(args)
+	wrappers := map[string]func() interface{}{
+		"name value": func() interface{} {
+			return NameValue("k", bound)
+		},
+		"name values": func() interface{} {
+			return NameValues(NameValue("k", bound))
+		},
+		"object": func() interface{} {
+			return NameValue("k", Object(NameValue("k", Array(bound))))
+		},
+	}
+	for name, wrap := range wrappers {
+		t.Run(name, func(t *testing.T) {
+			args["x"] = wrap()
+			_, err := jt.String(map[string]interface{}{"x": args["x"]})
+			require.Error(t, err)
+			require.True(t, strings.HasSuffix(err.Error(), "bound template is bound into itself"))
+			_, err = bound.JSONTData()
+			require.Error(t, err)
+		})
+	}
+
+	// not a cycle...
+	args["x"] = NameValue("k", 1)
+	str, err := jt.String(map[string]interface{}{"x": NameValue("k", bound)})
+	require.NoError(t, err)
+	require.Equal(t, `{"k":{"k":1}}`, str)
+}
+
+func TestBindRenderedOncePerRender(t *testing.T) {
+	calls := 0
+	inner := MustCompileNamedTemplate(`{"count":?count}`)
+	bound := inner.Bind(map[string]interface{}{"count": func(ctx context.Context) (interface{}, error) {
+		calls++
+		return calls, nil
+	}})
+	jt := MustCompileTemplate(`{"a":?1,"b":?1,"c":?2}`)
+	str, err := jt.String(bound, 0)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"count":1},"b":{"count":1},"c":0}`, str)
+	require.Equal(t, 1, calls)
+
+	_, err = jt.String(MustCompileTemplate(`[?]`).Bind(func() {}), 0)
+	require.Error(t, err)
+	require.Equal(t, "arg[0] at line 1, column 6: arg[0] at line 1, column 2: json: unsupported type: func()", err.Error())
+}
@@ -0,0 +1,203 @@
+package jsont
+
+import (
+	"bytes"
+	"context"
+	"sort"
+)
+
+// builder is implemented by values that build JSON (see Object and Array) - a builder can indicate that it
+// should be omitted from the object or array that contains it
+type builder interface {
+	buildData(ctx context.Context) (data []byte, omit bool, err error)
+}
+
+// ObjectBuilder builds a JSON object from name value pairs (see Object)
+//
+// Member values can themselves be builders (i.e. ObjectBuilder or ArrayBuilder) - so that small documents can
+// be built without a template, e.g.
+//   data, err := jsont.Object(
+//     jsont.NameValue("name", "bilbo"),
+//     jsont.NameValue("address", jsont.Object(jsont.NameValue("city", city)).OmitEmpty()),
+//     jsont.NameValue("tags", jsont.Array("hobbit", "burglar")),
+//   ).ToData()
+//
+// An ObjectBuilder can also be used as an arg value for a template
+type ObjectBuilder struct {
+	members   []*NameValuePair
+	omitEmpty bool
+	when      func() bool
+	sortKeys  bool
+}
+
+// Object creates a new ObjectBuilder with the specified members
+func Object(members ...*NameValuePair) *ObjectBuilder {
+	return &ObjectBuilder{
+		members: members,
+	}
+}
+
+// Add adds members to the object
+func (ob *ObjectBuilder) Add(members ...*NameValuePair) *ObjectBuilder {
+	ob.members = append(ob.members, members...)
+	return ob
+}
+
+// OmitEmpty makes the object omitted from its containing object or array when it has no members (or all
+// of its members are omitted)
+func (ob *ObjectBuilder) OmitEmpty() *ObjectBuilder {
+	ob.omitEmpty = true
+	return ob
+}
+
+// When makes the object conditional - the object is omitted from its containing object or array when the
+// condition func returns false
+//
+// Note: when the object is not contained (i.e. is used directly or as a template arg value) an omitted object is rendered as null
+func (ob *ObjectBuilder) When(cond func() bool) *ObjectBuilder {
+	ob.when = cond
+	return ob
+}
+
+// SortKeys makes the object members rendered in name order (rather than the order in which they were added)
+func (ob *ObjectBuilder) SortKeys() *ObjectBuilder {
+	ob.sortKeys = true
+	return ob
+}
+
+// ToData renders the object as JSON data
+func (ob *ObjectBuilder) ToData() ([]byte, error) {
+	return builtData(ob.buildData(context.Background()))
+}
+
+// JSONTData implements DataProvider
+func (ob *ObjectBuilder) JSONTData() ([]byte, error) {
+	return ob.ToData()
+}
+
+func (ob *ObjectBuilder) contextData(ctx context.Context) ([]byte, error) {
+	return builtData(ob.buildData(ctx))
+}
+
+func (ob *ObjectBuilder) buildData(ctx context.Context) (data []byte, omit bool, err error) {
+	if ob.when != nil && !ob.when() {
+		return nil, true, nil
+	}
+	members := ob.members
+	if ob.sortKeys {
+		members = make([]*NameValuePair, 0, len(ob.members))
+		for _, nvp := range ob.members {
+			if nvp != nil {
+				members = append(members, nvp)
+			}
+		}
+		sort.SliceStable(members, func(i, j int) bool {
+			return members[i].name < members[j].name
+		})
+	}
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	added, err := writeNameValues(ctx, &buffer, members, false)
+	if err != nil {
+		return nil, false, err
+	} else if !added && ob.omitEmpty {
+		return nil, true, nil
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), false, nil
+}
+
+// ArrayBuilder builds a JSON array from items (see Array)
+//
+// Items can themselves be builders (i.e. ObjectBuilder or ArrayBuilder) - builder items that are omitted
+// (see ObjectBuilder.OmitEmpty and ObjectBuilder.When) are not rendered in the array
+//
+// An ArrayBuilder can also be used as an arg value for a template
+type ArrayBuilder struct {
+	items     []interface{}
+	omitEmpty bool
+	when      func() bool
+}
+
+// Array creates a new ArrayBuilder with the specified items
+func Array(items ...interface{}) *ArrayBuilder {
+	return &ArrayBuilder{
+		items: items,
+	}
+}
+
+// Add adds items to the array
+func (ab *ArrayBuilder) Add(items ...interface{}) *ArrayBuilder {
+	ab.items = append(ab.items, items...)
+	return ab
+}
+
+// OmitEmpty makes the array omitted from its containing object or array when it has no items (or all
+// of its items are omitted)
+func (ab *ArrayBuilder) OmitEmpty() *ArrayBuilder {
+	ab.omitEmpty = true
+	return ab
+}
+
+// When makes the array conditional - the array is omitted from its containing object or array when the
+// condition func returns false
+//
+// Note: when the array is not contained (i.e. is used directly or as a template arg value) an omitted array is rendered as null
+func (ab *ArrayBuilder) When(cond func() bool) *ArrayBuilder {
+	ab.when = cond
+	return ab
+}
+
+// ToData renders the array as JSON data
+func (ab *ArrayBuilder) ToData() ([]byte, error) {
+	return builtData(ab.buildData(context.Background()))
+}
+
+// JSONTData implements DataProvider
+func (ab *ArrayBuilder) JSONTData() ([]byte, error) {
+	return ab.ToData()
+}
+
+func (ab *ArrayBuilder) contextData(ctx context.Context) ([]byte, error) {
+	return builtData(ab.buildData(ctx))
+}
+
+func (ab *ArrayBuilder) buildData(ctx context.Context) (data []byte, omit bool, err error) {
+	if ab.when != nil && !ab.when() {
+		return nil, true, nil
+	}
+	var buffer bytes.Buffer
+	buffer.WriteByte('[')
+	added := false
+	for _, item := range ab.items {
+		var iData []byte
+		if b, ok := item.(builder); ok {
+			var iOmit bool
+			if iData, iOmit, err = b.buildData(ctx); err != nil {
+				return nil, false, err
+			} else if iOmit {
+				continue
+			}
+		} else if iData, err = argValueToData(ctx, item); err != nil {
+			return nil, false, err
+		}
+		if added {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(iData)
+		added = true
+	}
+	if !added && ab.omitEmpty {
+		return nil, true, nil
+	}
+	buffer.WriteByte(']')
+	return buffer.Bytes(), false, nil
+}
+
+// builtData returns the data from a builder - where a builder that is omitted (but not contained) is rendered as null
+func builtData(data []byte, omit bool, err error) ([]byte, error) {
+	if omit && err == nil {
+		return nullData, nil
+	}
+	return data, err
+}
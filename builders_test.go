@@ -0,0 +1,89 @@
+package jsont
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestObject(t *testing.T) {
+	data, err := Object(
+		NameValue("name", "bilbo"),
+		NameValue("age", 111),
+		NameValue("address", Object(NameValue("city", "Hobbiton"))),
+		NameValue("tags", Array("hobbit", "burglar")),
+		nil,
+	).ToData()
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"bilbo","age":111,"address":{"city":"Hobbiton"},"tags":["hobbit","burglar"]}`, string(data))
+
+	data, err = Object().ToData()
+	require.NoError(t, err)
+	require.Equal(t, `{}`, string(data))
+
+	data, err = Object(NameValue("b", 2), nil, NameValue("a", 1)).Add(NameValue("c", 3)).SortKeys().ToData()
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":2,"c":3}`, string(data))
+	// sorted by unescaped name...
+	data, err = Object(NameValue("c", 3), NameValueRaw(`\u0062`, 2), NameValue("a", 1)).SortKeys().ToData()
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"\u0062":2,"c":3}`, string(data))
+
+	_, err = Object(NameValue("a", Object(NameValue("b", func() {})))).ToData()
+	require.Error(t, err)
+}
+
+func TestObjectOmitEmptyAndWhen(t *testing.T) {
+	include := false
+	ob := Object(
+		NameValue("a", Object(NameValue("x", nil).OmitEmpty()).OmitEmpty()),
+		NameValue("b", Array().OmitEmpty()),
+		NameValue("c", Object(NameValue("x", 1)).When(func() bool { return include })),
+		NameValue("d", Array(Object().OmitEmpty(), 1, Array(2).When(func() bool { return include }))),
+	)
+	data, err := ob.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `{"d":[1]}`, string(data))
+
+	include = true
+	data, err = ob.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `{"c":{"x":1},"d":[1,[2]]}`, string(data))
+
+	// not contained - so rendered as null...
+	data, err = Object().OmitEmpty().ToData()
+	require.NoError(t, err)
+	require.Equal(t, `null`, string(data))
+	data, err = Array(1).When(func() bool { return false }).ToData()
+	require.NoError(t, err)
+	require.Equal(t, `null`, string(data))
+}
+
+func TestArray(t *testing.T) {
+	data, err := Array(1, nil, "a", []byte(`{"raw":true}`), Object(NameValue("x", true))).Add(Array()).ToData()
+	require.NoError(t, err)
+	require.Equal(t, `[1,null,"a",{"raw":true},{"x":true},[]]`, string(data))
+
+	_, err = Array(func() {}).ToData()
+	require.Error(t, err)
+	_, err = Array(Array(func() {})).ToData()
+	require.Error(t, err)
+}
+
+func TestBuildersAsArgs(t *testing.T) {
+	var _ DataProvider = Object()
+	var _ DataProvider = Array()
+
+	jt := MustCompileNamedTemplate(`{"user":?user,"tags":?tags,?extra}`)
+	str, err := jt.String(map[string]interface{}{
+		"user":  Object(NameValue("name", "bilbo")),
+		"tags":  Array("hobbit"),
+		"extra": NameValues(NameValue("address", Object().OmitEmpty()), NameValue("ok", true)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, `{"user":{"name":"bilbo"},"tags":["hobbit"],"ok":true}`, str)
+
+	jt = MustCompileNamedTemplate(`{"user":?user}`, OptionValidateRawArgs)
+	str, err = jt.String(map[string]interface{}{"user": Object().When(func() bool { return false })})
+	require.NoError(t, err)
+	require.Equal(t, `{"user":null}`, str)
+}
@@ -2,7 +2,17 @@ package jsont
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 var nullData = []byte{'n', 'u', 'l', 'l'}
@@ -13,6 +23,18 @@ type jsonTemplateToken struct {
 	fixed      bool
 	fixedValue []byte
 	argName    string
+	argIndex   int
+	fallbacks  []string
+	filters    []filterCall
+	pos        Position
+}
+
+// chainNames returns the arg name followed by any fallback arg names
+func (tkn jsonTemplateToken) chainNames() []string {
+	if len(tkn.fallbacks) == 0 {
+		return []string{tkn.argName}
+	}
+	return append([]string{tkn.argName}, tkn.fallbacks...)
 }
 
 type tokens []jsonTemplateToken
@@ -22,6 +44,7 @@ func (t tokens) joinContiguousFixed() tokens {
 	result := make(tokens, 0)
 	if l > 0 {
 		var curr []byte
+		var currPos Position
 		for _, tkn := range t {
 			if tkn.fixed {
 				if curr != nil {
@@ -29,23 +52,62 @@ func (t tokens) joinContiguousFixed() tokens {
 				} else {
 					curr = make([]byte, 0, len(tkn.fixedValue))
 					curr = append(curr, tkn.fixedValue...)
+					currPos = tkn.pos
 				}
 			} else {
 				if curr != nil {
-					result = append(result, jsonTemplateToken{fixed: true, fixedValue: curr})
+					result = append(result, jsonTemplateToken{fixed: true, fixedValue: curr, pos: currPos})
 				}
 				result = append(result, tkn)
 				curr = nil
 			}
 		}
 		if curr != nil {
-			result = append(result, jsonTemplateToken{fixed: true, fixedValue: curr})
+			result = append(result, jsonTemplateToken{fixed: true, fixedValue: curr, pos: currPos})
+		}
+	}
+	return result
+}
+
+// argIndexMarkers returns the number of markers for a positional arg index
+func (t tokens) argIndexMarkers(argIndex int) int {
+	result := 0
+	for _, tkn := range t {
+		if !tkn.fixed && tkn.argName == "" && tkn.argIndex == argIndex {
+			result++
 		}
 	}
 	return result
 }
 
-func argValueToData(v interface{}) ([]byte, error) {
+// renderWriter is the writer that templates render into (implemented by both bytes.Buffer and strings.Builder)
+type renderWriter interface {
+	io.Writer
+	Len() int
+	Grow(n int)
+}
+
+// dataWriter is implemented by arg values that can render directly into the template output (e.g. bound templates)
+type dataWriter interface {
+	DataProvider
+	writeData(ctx context.Context, w renderWriter) error
+}
+
+// contextDataProvider is implemented by arg values that provide their data from other values (e.g. NameValuePair) - so
+// that the render context is passed on to those values (e.g. so that a bound template value knows what is being rendered)
+type contextDataProvider interface {
+	contextData(ctx context.Context) ([]byte, error)
+}
+
+// DataProvider is the interface that arg values can implement to provide pre-rendered JSON data - the data
+// provided is written into the template output as is (i.e. it is not marshalled)
+//
+// NameValuePair and NameValuePairs implement this interface (to provide object members)
+type DataProvider interface {
+	JSONTData() ([]byte, error)
+}
+
+func argValueToData(ctx context.Context, v interface{}) ([]byte, error) {
 	switch vt := v.(type) {
 	case nil:
 		return nullData, nil
@@ -53,10 +115,16 @@ func argValueToData(v interface{}) ([]byte, error) {
 		return vt, nil
 	case json.RawMessage:
 		return vt, nil
-	case *NameValuePair:
-		return vt.ToData()
-	case *NameValuePairs:
-		return vt.ToData()
+	case dataWriter:
+		var buffer bytes.Buffer
+		if err := vt.writeData(ctx, &buffer); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	case contextDataProvider:
+		return vt.contextData(ctx)
+	case DataProvider:
+		return vt.JSONTData()
 	default:
 		if jArg, err := json.Marshal(v); err == nil {
 			return jArg, nil
@@ -66,38 +134,323 @@ func argValueToData(v interface{}) ([]byte, error) {
 	}
 }
 
+// getArgsData converts positional args to JSON data - when a collector is supplied, marshalling errors
+// are added to the collector (rather than returned)
+//
+// Lazy arg values are resolved using the context - and an error is returned if the context is cancelled
+//
+// Args that can write directly to the output (e.g. bound templates) are not converted - they are returned
+// in writers (by arg index)
+//
+// Args beyond the args count (i.e. extra args supplied to a non-strict template) are ignored
+func getArgsData(ctx context.Context, args []interface{}, argsCount int, tkns tokens, validation jsonValidation, collector *argErrorsCollector) (argsData [][]byte, writers map[int]dataWriter, argsLen int, err error) {
+	argsData = make([][]byte, argsCount)
+	argsLen = 0
+	l := len(args)
+	if l > argsCount {
+		l = argsCount
+	}
+	for i := 0; i < l; i++ {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var ad []byte
+		v, e := resolveLazyArg(ctx, args[i])
+		if dw, ok := v.(dataWriter); ok && e == nil {
+			if tkns.argIndexMarkers(i) <= 1 {
+				if writers == nil {
+					writers = map[int]dataWriter{}
+				}
+				writers[i] = dw
+				continue
+			}
+			// used by more than one marker - so rendered once (rather than written directly for each marker)...
+			var buffer bytes.Buffer
+			if e = dw.writeData(ctx, &buffer); e == nil {
+				ad = buffer.Bytes()
+			}
+		} else if e == nil {
+			ad, e = argValueToData(ctx, v)
+		}
+		if e == nil && validation == validateRawArgs {
+			e = checkRawArgData(v, ad)
+		}
+		if e == nil {
+			argsData[i] = ad
+			argsLen += len(ad)
+		} else {
+			e = &ArgMarshalError{
+				ArgIndex: i,
+				Position: tkns.argIndexPosition(i),
+				Err:      e,
+			}
+			if collector == nil {
+				err = e
+				break
+			}
+			collector.add(strconv.Itoa(i), e)
+		}
+	}
+	for i := l; i < argsCount; i++ {
+		argsData[i] = nullData
+		argsLen += nullDataLen
+	}
+	return
+}
+
+// writePositionalArg writes the data for a positional arg marker to the writer
+func writePositionalArg(ctx context.Context, w renderWriter, tkn jsonTemplateToken, argsData [][]byte, writers map[int]dataWriter, validator *outputValidator) error {
+	validator.add(tkn, w.Len())
+	if dw, ok := writers[tkn.argIndex]; ok {
+		if err := dw.writeData(ctx, w); err != nil {
+			return &ArgMarshalError{
+				ArgIndex: tkn.argIndex,
+				Position: tkn.pos,
+				Err:      err,
+			}
+		}
+		return nil
+	}
+	w.Write(argsData[tkn.argIndex])
+	return nil
+}
+
+// renderTokens renders the tokens into the writer - positional arg markers are written from the positional
+// args data (see getArgsData) and named arg markers are written by the named template (using the resolver)
+func renderTokens(ctx context.Context, w renderWriter, tkns tokens, argsData [][]byte, writers map[int]dataWriter, named *jsonNamedTemplate, resolver ArgResolver, validator *outputValidator, collector *argErrorsCollector) error {
+	for _, tkn := range tkns {
+		if tkn.fixed {
+			w.Write(tkn.fixedValue)
+			continue
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		argKey := tkn.argName
+		var err error
+		if argKey == "" {
+			argKey = strconv.Itoa(tkn.argIndex)
+			err = writePositionalArg(ctx, w, tkn, argsData, writers, validator)
+		} else {
+			err = named.writeNamedArg(ctx, w, tkn, resolver, validator)
+		}
+		if err != nil {
+			if collector == nil {
+				return err
+			}
+			collector.add(argKey, err)
+		}
+	}
+	return collector.error()
+}
+
+// resolveLazyArg resolves a lazy arg value (i.e. a func(context.Context) (interface{}, error)) - other values
+// are returned as is
+func resolveLazyArg(ctx context.Context, v interface{}) (interface{}, error) {
+	if fn, ok := v.(func(context.Context) (interface{}, error)); ok {
+		return fn(ctx)
+	}
+	return v, nil
+}
+
+func checkArgsCount(strict bool, argsCount int, args []interface{}) error {
+	if strict && len(args) != argsCount {
+		return newArgCountError(argsCount, len(args), "expected %d args but supplied %d args")
+	}
+	return nil
+}
+
 type NameValuePair struct {
-	name      string
-	nameData  []byte
-	value     interface{}
-	omitEmpty bool
+	name     string // the (unescaped) name
+	nameData []byte
+	nameErr  error
+	value    interface{}
+	omit     omitMode
+	quoted   bool // the value is encoded as a JSON string (see NameValuesFrom)
+	when     func() bool
 }
 
+// omitMode determines when a NameValuePair is omitted (see NameValuePair.OmitEmpty, NameValuePair.OmitZero and NameValuePair.OmitEmptyJSON)
+type omitMode int
+
+const (
+	omitNever omitMode = iota
+	omitNil
+	omitZero
+	omitEmptyJSON
+	omitEmptyJSONOrZero
+)
+
+// NameValue creates a new NameValuePair - the name is JSON escaped
 func NameValue(name string, value interface{}) *NameValuePair {
-	return &NameValuePair{
+	result := &NameValuePair{
 		name:     name,
-		nameData: []byte(`"` + name + `":`),
+		nameData: nameToData(name),
 		value:    value,
 	}
+	if !utf8.ValidString(name) {
+		// the name is as rendered (i.e. with invalid utf-8 replaced)...
+		result.name, _ = dataToName(result.nameData)
+	}
+	return result
+}
+
+// NameValueRaw creates a new NameValuePair with a name that is already JSON escaped (i.e. the name is used
+// as is - and is not escaped)
+//
+// The escaped name is validated - rendering a pair whose escaped name is not valid (e.g. contains an unescaped '"')
+// fails with an error that is ErrInvalidJSON
+func NameValueRaw(escapedName string, value interface{}) *NameValuePair {
+	result := &NameValuePair{
+		name:     escapedName,
+		nameData: []byte(`"` + escapedName + `":`),
+		value:    value,
+	}
+	if name, err := dataToName(result.nameData); err == nil {
+		result.name = name
+	} else {
+		result.nameErr = fmt.Errorf("%w escaped name '%s': %s", ErrInvalidJSON, escapedName, err.Error())
+	}
+	return result
+}
+
+// dataToName decodes the name from name data (i.e. the quoted, escaped name followed by ':')
+func dataToName(nameData []byte) (name string, err error) {
+	if !utf8.Valid(nameData) {
+		return "", errors.New("invalid utf-8")
+	}
+	err = json.Unmarshal(nameData[:len(nameData)-1], &name)
+	return
+}
+
+func nameToData(name string) []byte {
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c < 0x20 || c == '"' || c == '\\' || c >= utf8.RuneSelf {
+			// needs escaping (or checking for invalid utf-8)...
+			var buffer bytes.Buffer
+			enc := json.NewEncoder(&buffer)
+			enc.SetEscapeHTML(false)
+			_ = enc.Encode(name)
+			data := bytes.TrimRight(buffer.Bytes(), "\n")
+			return append(data, ':')
+		}
+	}
+	return []byte(`"` + name + `":`)
 }
 
+// OmitEmpty makes the pair omitted when the value is nil
 func (nvp *NameValuePair) OmitEmpty() *NameValuePair {
-	nvp.omitEmpty = true
+	nvp.omit = omitNil
+	return nvp
+}
+
+// OmitZero makes the pair omitted when the value is the zero value for its type - or, if the value implements
+// an IsZero() bool method, when that method returns true (mirroring encoding/json's omitzero)
+func (nvp *NameValuePair) OmitZero() *NameValuePair {
+	nvp.omit = omitZero
+	return nvp
+}
+
+// OmitEmptyJSON makes the pair omitted when the value is empty as defined by encoding/json's omitempty - i.e.
+// false, 0, a nil pointer or interface value, or any empty array, slice, map or string
+func (nvp *NameValuePair) OmitEmptyJSON() *NameValuePair {
+	nvp.omit = omitEmptyJSON
+	return nvp
+}
+
+// When makes the pair conditional - the pair is only included when the condition func returns true (the
+// condition func is called each time the pair is rendered)
+func (nvp *NameValuePair) When(cond func() bool) *NameValuePair {
+	nvp.when = cond
 	return nvp
 }
 
-func (nvp *NameValuePair) ToData() (result []byte, err error) {
+// If makes the pair conditional - the pair is only included when the condition is true
+func (nvp *NameValuePair) If(cond bool) *NameValuePair {
+	nvp.when = func() bool {
+		return cond
+	}
+	return nvp
+}
+
+func (nvp *NameValuePair) omitted(v interface{}) bool {
+	switch nvp.omit {
+	case omitNil:
+		return v == nil
+	case omitZero:
+		return isZeroValue(v)
+	case omitEmptyJSON:
+		return isEmptyJSONValue(v)
+	case omitEmptyJSONOrZero:
+		return isEmptyJSONValue(v) || isZeroValue(v)
+	}
+	return false
+}
+
+func isZeroValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	if z, ok := v.(interface{ IsZero() bool }); ok {
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return true
+		}
+		return z.IsZero()
+	}
+	return rv.IsZero()
+}
+
+func isEmptyJSONValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func (nvp *NameValuePair) ToData() ([]byte, error) {
+	return nvp.contextData(context.Background())
+}
+
+func (nvp *NameValuePair) contextData(ctx context.Context) (result []byte, err error) {
+	if nvp.nameErr != nil {
+		return nil, nvp.nameErr
+	} else if nvp.when != nil && !nvp.when() {
+		return
+	}
 	useValue := nvp.value
 	if gdfn, ok := useValue.(func(string) interface{}); ok {
 		useValue = gdfn(nvp.name)
 	}
-	if nvp.omitEmpty && useValue == nil {
+	if nvp.omitted(useValue) {
 		return
 	}
-	vData, err := argValueToData(useValue)
-	if err != nil {
+	var vData []byte
+	if b, ok := useValue.(builder); ok {
+		var omit bool
+		if vData, omit, err = b.buildData(ctx); err != nil || omit {
+			return nil, err
+		}
+	} else if vData, err = argValueToData(ctx, useValue); err != nil {
 		return nil, err
 	}
+	if nvp.quoted && string(vData) != "null" {
+		vData, _ = json.Marshal(string(vData))
+	}
 	capacity := checkedCapacity(len(nvp.nameData), len(vData))
 	result = make([]byte, 0, capacity)
 	result = append(result, nvp.nameData...)
@@ -105,6 +458,11 @@ func (nvp *NameValuePair) ToData() (result []byte, err error) {
 	return
 }
 
+// JSONTData implements DataProvider
+func (nvp *NameValuePair) JSONTData() ([]byte, error) {
+	return nvp.ToData()
+}
+
 func checkedCapacity(sz1, sz2 int) int {
 	if tot := sz1 + sz2; tot < sz1 || tot < sz2 {
 		return 0
@@ -114,7 +472,8 @@ func checkedCapacity(sz1, sz2 int) int {
 }
 
 type NameValuePairs struct {
-	pairs []*NameValuePair
+	pairs           []*NameValuePair
+	checkDuplicates bool
 }
 
 func NameValues(pairs ...*NameValuePair) *NameValuePairs {
@@ -123,21 +482,295 @@ func NameValues(pairs ...*NameValuePair) *NameValuePairs {
 	}
 }
 
+// NameValuesFrom creates NameValuePairs from a map or struct (or pointer to a struct)
+//
+// For a map, the keys must be strings (or integers) - and the pairs are ordered by key
+//
+// For a struct, the pairs are the fields that encoding/json would encode (in the same order) - following the same
+// rules, i.e. the field names, omission and quoting follow the struct's json tags (a tag name of "-" is skipped,
+// "omitempty" uses NameValuePair.OmitEmptyJSON, "omitzero" uses NameValuePair.OmitZero - with both, the pair is omitted
+// if either applies - and ",string" encodes the value as a JSON string), untagged embedded structs (and pointers to
+// structs) are flattened and, where fields have the same name, only the dominant field is used
+func NameValuesFrom(v interface{}) (*NameValuePairs, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		return nameValuesFromMap(rv)
+	case reflect.Struct:
+		return NameValues(nameValuesFromStruct(rv)...), nil
+	}
+	return nil, fmt.Errorf("cannot create name values from type '%T'", v)
+}
+
+func nameValuesFromMap(rv reflect.Value) (*NameValuePairs, error) {
+	var keyName func(k reflect.Value) string
+	switch rv.Type().Key().Kind() {
+	case reflect.String:
+		keyName = reflect.Value.String
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		keyName = func(k reflect.Value) string {
+			return strconv.FormatInt(k.Int(), 10)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		keyName = func(k reflect.Value) string {
+			return strconv.FormatUint(k.Uint(), 10)
+		}
+	default:
+		return nil, fmt.Errorf("cannot create name values from map with key type '%s'", rv.Type().Key())
+	}
+	keys := rv.MapKeys()
+	pairs := make([]*NameValuePair, len(keys))
+	for i, k := range keys {
+		pairs[i] = NameValue(keyName(k), rv.MapIndex(k).Interface())
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].name < pairs[j].name
+	})
+	return NameValues(pairs...), nil
+}
+
+func nameValuesFromStruct(rv reflect.Value) []*NameValuePair {
+	fields := structFields(rv.Type())
+	pairs := make([]*NameValuePair, 0, len(fields))
+	for _, fld := range fields {
+		if fv, ok := structFieldValue(rv, fld.index); ok {
+			nvp := NameValue(fld.name, fv.Interface())
+			nvp.omit = fld.omit
+			nvp.quoted = fld.quoted
+			pairs = append(pairs, nvp)
+		}
+	}
+	return pairs
+}
+
+// structField is a struct field (possibly promoted from an embedded struct) that is encoded by encoding/json
+type structField struct {
+	name   string
+	tagged bool
+	index  []int
+	typ    reflect.Type
+	omit   omitMode
+	quoted bool
+}
+
+// structFields returns the fields of a struct type that encoding/json would encode (in encoding order) - following
+// the same rules as encoding/json, i.e. embedded structs (and pointers to structs) are flattened and, where more
+// than one field has the same name, the dominant field is used (or, if there is no dominant field, none are used)
+func structFields(rt reflect.Type) []structField {
+	if cached, ok := structFieldsCache.Load(rt); ok {
+		return cached.([]structField)
+	}
+	fields, _ := structFieldsCache.LoadOrStore(rt, typeStructFields(rt))
+	return fields.([]structField)
+}
+
+// structFieldsCache is the cache of struct fields by struct type (the fields of a type are only determined once)
+var structFieldsCache sync.Map
+
+func typeStructFields(rt reflect.Type) []structField {
+	fields := make([]structField, 0)
+	current := make([]structField, 0)
+	next := []structField{{typ: rt}}
+	count := map[reflect.Type]int{}
+	nextCount := map[reflect.Type]int{}
+	visited := map[reflect.Type]bool{}
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+		for _, f := range current {
+			if visited[f.typ] {
+				continue
+			}
+			visited[f.typ] = true
+			for i := 0; i < f.typ.NumField(); i++ {
+				sf := f.typ.Field(i)
+				if sf.Anonymous {
+					et := sf.Type
+					if et.Kind() == reflect.Ptr {
+						et = et.Elem()
+					}
+					if !sf.IsExported() && et.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := make([]int, len(f.index)+1)
+				copy(index, f.index)
+				index[len(f.index)] = i
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					fld := structField{name: name, tagged: name != "", index: index, typ: ft}
+					if name == "" {
+						fld.name = sf.Name
+					}
+					fld.omit, fld.quoted = structFieldOptions(opts, ft)
+					fields = append(fields, fld)
+					if count[f.typ] > 1 {
+						// the embedding struct occurs more than once at this depth - so add a duplicate of the field
+						// (which then means that the field is not dominant)...
+						fields = append(fields, fields[len(fields)-1])
+					}
+					continue
+				}
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, structField{name: ft.Name(), index: index, typ: ft})
+				}
+			}
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		fi, fj := fields[i], fields[j]
+		if fi.name != fj.name {
+			return fi.name < fj.name
+		} else if len(fi.index) != len(fj.index) {
+			return len(fi.index) < len(fj.index)
+		} else if fi.tagged != fj.tagged {
+			return fi.tagged
+		}
+		return lessIndex(fi.index, fj.index)
+	})
+	result := make([]structField, 0, len(fields))
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if j-i == 1 || len(fields[i].index) < len(fields[i+1].index) || fields[i].tagged != fields[i+1].tagged {
+			result = append(result, fields[i])
+		}
+		i = j
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return lessIndex(result[i].index, result[j].index)
+	})
+	return result
+}
+
+func lessIndex(a, b []int) bool {
+	for k, x := range a {
+		if k >= len(b) {
+			return false
+		} else if x != b[k] {
+			return x < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// structFieldOptions determines the omission and quoting of a struct field from its json tag options
+func structFieldOptions(opts string, ft reflect.Type) (omit omitMode, quoted bool) {
+	omitEmpty, omitZeroValue := false, false
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "omitempty":
+			omitEmpty = true
+		case "omitzero":
+			omitZeroValue = true
+		case "string":
+			switch ft.Kind() {
+			case reflect.Bool, reflect.String,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64:
+				quoted = true
+			}
+		}
+	}
+	switch {
+	case omitEmpty && omitZeroValue:
+		omit = omitEmptyJSONOrZero
+	case omitEmpty:
+		omit = omitEmptyJSON
+	case omitZeroValue:
+		omit = omitZero
+	}
+	return
+}
+
+// structFieldValue returns the value of a (possibly promoted) struct field - returning false if the field is
+// promoted through a nil embedded pointer (or the value cannot be used)
+func structFieldValue(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, rv.CanInterface()
+}
+
+// Add adds pairs
+func (nvps *NameValuePairs) Add(pairs ...*NameValuePair) *NameValuePairs {
+	nvps.pairs = append(nvps.pairs, pairs...)
+	return nvps
+}
+
+// CheckDuplicates makes rendering the pairs fail (with an error that is ErrDuplicateName) when more than one
+// (non-omitted) pair has the same (unescaped) name
+func (nvps *NameValuePairs) CheckDuplicates() *NameValuePairs {
+	nvps.checkDuplicates = true
+	return nvps
+}
+
 func (nvps *NameValuePairs) ToData() ([]byte, error) {
+	return nvps.contextData(context.Background())
+}
+
+func (nvps *NameValuePairs) contextData(ctx context.Context) ([]byte, error) {
 	var buffer bytes.Buffer
-	added := false
-	for _, nvp := range nvps.pairs {
+	if _, err := writeNameValues(ctx, &buffer, nvps.pairs, nvps.checkDuplicates); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// writeNameValues writes the comma separated data of the pairs to the buffer - returning whether any pairs
+// were written (i.e. were not omitted)
+func writeNameValues(ctx context.Context, buffer *bytes.Buffer, pairs []*NameValuePair, checkDuplicates bool) (added bool, err error) {
+	var seen map[string]bool
+	if checkDuplicates {
+		seen = map[string]bool{}
+	}
+	for _, nvp := range pairs {
 		if nvp != nil {
-			if nvData, err := nvp.ToData(); err == nil && len(nvData) > 0 {
+			if nvData, err := nvp.contextData(ctx); err == nil && len(nvData) > 0 {
+				if seen != nil {
+					// compared using the unescaped name (so that differently escaped names are duplicates)...
+					if seen[nvp.name] {
+						return added, fmt.Errorf("%w '%s'", ErrDuplicateName, nvp.name)
+					}
+					seen[nvp.name] = true
+				}
 				if added {
 					buffer.WriteByte(',')
 				}
 				buffer.Write(nvData)
 				added = true
 			} else if err != nil {
-				return nil, err
+				return added, err
 			}
 		}
 	}
-	return buffer.Bytes(), nil
+	return
+}
+
+// JSONTData implements DataProvider
+func (nvps *NameValuePairs) JSONTData() ([]byte, error) {
+	return nvps.ToData()
 }
@@ -1,7 +1,9 @@
 package jsont
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -73,27 +75,27 @@ func TestJoinContiguousFixed(t *testing.T) {
 }
 
 func TestArgValueToData(t *testing.T) {
-	vdata, err := argValueToData("foo")
+	vdata, err := argValueToData(context.Background(), "foo")
 	assert.Nil(t, err)
 	assert.Equal(t, 5, len(vdata))
 	assert.Equal(t, `"foo"`, string(vdata[:]))
 
-	vdata, err = argValueToData(true)
+	vdata, err = argValueToData(context.Background(), true)
 	assert.Nil(t, err)
 	assert.Equal(t, 4, len(vdata))
 	assert.Equal(t, `true`, string(vdata[:]))
 
-	vdata, err = argValueToData(1)
+	vdata, err = argValueToData(context.Background(), 1)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(vdata))
 	assert.Equal(t, `1`, string(vdata[:]))
 
-	vdata, err = argValueToData(1.2)
+	vdata, err = argValueToData(context.Background(), 1.2)
 	assert.Nil(t, err)
 	assert.Equal(t, 3, len(vdata))
 	assert.Equal(t, `1.2`, string(vdata[:]))
 
-	vdata, err = argValueToData(struct {
+	vdata, err = argValueToData(context.Background(), struct {
 		Foo string
 	}{"bar"})
 	assert.Nil(t, err)
@@ -101,20 +103,20 @@ func TestArgValueToData(t *testing.T) {
 	assert.Equal(t, `{"Foo":"bar"}`, string(vdata[:]))
 
 	origData := vdata
-	vdata, err = argValueToData(origData)
+	vdata, err = argValueToData(context.Background(), origData)
 	assert.Nil(t, err)
 	assert.Equal(t, 13, len(vdata))
 	assert.Equal(t, `{"Foo":"bar"}`, string(vdata[:]))
 	assert.Equalf(t, vdata, origData, "must be the same")
 
 	origRawData := json.RawMessage(vdata)
-	vdata, err = argValueToData(origRawData)
+	vdata, err = argValueToData(context.Background(), origRawData)
 	assert.Nil(t, err)
 	assert.Equal(t, 13, len(vdata))
 	assert.Equal(t, `{"Foo":"bar"}`, string(vdata[:]))
 	assert.Equalf(t, vdata, origData, "must be the same")
 
-	_, err = argValueToData(func() {})
+	_, err = argValueToData(context.Background(), func() {})
 	assert.NotNil(t, err)
 }
 
@@ -181,6 +183,277 @@ func TestNameValueFunc(t *testing.T) {
 	require.Equal(t, `{"foo":2}`, str)
 }
 
+func TestNameValueEscaping(t *testing.T) {
+	testCases := []struct {
+		name   string
+		expect string
+	}{
+		{"foo", `"foo":1`},
+		{`a"b`, `"a\"b":1`},
+		{`a\b`, `"a\\b":1`},
+		{"a\nb\tc\x01", `"a\nb\tc\u0001":1`},
+		{"<&>", `"<&>":1`},
+		{"äöü", `"äöü":1`},
+		{"bad\xffutf8", "\"bad\ufffdutf8\":1"},
+		{"", `"":1`},
+	}
+	for i, tc := range testCases {
+		data, err := NameValue(tc.name, 1).ToData()
+		require.NoError(t, err)
+		require.Equal(t, tc.expect, string(data), "test case %d", i)
+		require.True(t, json.Valid([]byte("{"+string(data)+"}")), "test case %d", i)
+	}
+
+	data, err := NameValueRaw(`a\"b`, 1).ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"a\"b":1`, string(data))
+
+	// raw names are validated...
+	for _, invalid := range []string{`a"b`, `a\`, `\x`, "a\nb", "bad\xff", `\u00`} {
+		_, err = NameValueRaw(invalid, 1).ToData()
+		require.Error(t, err, "raw name %q", invalid)
+		require.True(t, errors.Is(err, ErrInvalidJSON))
+	}
+	_, err = NameValues(NameValue("a", 1), NameValueRaw(`a"b`, 1)).ToData()
+	require.Error(t, err)
+	require.Equal(t, `invalid JSON escaped name 'a"b': invalid character 'b' after top-level value`, err.Error())
+}
+
+func TestNameValuesCheckDuplicates(t *testing.T) {
+	nvps := NameValues(NameValue("a", 1), NameValue("b", 2), NameValue("a", 3))
+	data, err := nvps.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"a":1,"b":2,"a":3`, string(data))
+
+	_, err = nvps.CheckDuplicates().ToData()
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrDuplicateName))
+	require.Equal(t, "duplicate name 'a'", err.Error())
+
+	// escaped and raw names are compared unescaped...
+	_, err = NameValues(NameValue(`a"b`, 1), NameValueRaw(`a\"b`, 2)).CheckDuplicates().ToData()
+	require.Error(t, err)
+	require.Equal(t, `duplicate name 'a"b'`, err.Error())
+	_, err = NameValues(NameValue("é", 1), NameValueRaw(`\u00e9`, 2)).CheckDuplicates().ToData()
+	require.Error(t, err)
+	require.Equal(t, `duplicate name 'é'`, err.Error())
+	_, err = NameValues(NameValue("bad\xff", 1), NameValue("bad\ufffd", 2)).CheckDuplicates().ToData()
+	require.Error(t, err)
+
+	// omitted pairs are not duplicates...
+	data, err = NameValues(NameValue("a", nil).OmitEmpty(), NameValue("a", 1), nil).CheckDuplicates().ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"a":1`, string(data))
+
+	jt := MustCompileTemplate(`{?}`)
+	_, err = jt.String(NameValues(NameValue("a", 1), NameValue("a", 2)).CheckDuplicates())
+	require.Error(t, err)
+	require.Equal(t, "arg[0] at line 1, column 2: duplicate name 'a'", err.Error())
+}
+
+func TestNameValueWhenAndIf(t *testing.T) {
+	include := false
+	nvps := NameValues(
+		NameValue("a", 1).When(func() bool { return include }),
+		NameValue("b", 2).If(true),
+		NameValue("c", 3).If(false),
+	)
+	data, err := nvps.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"b":2`, string(data))
+	include = true
+	data, err = nvps.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"a":1,"b":2`, string(data))
+
+	// condition is checked before lazy value is evaluated...
+	called := false
+	data, err = NameValue("a", func(name string) interface{} {
+		called = true
+		return 1
+	}).If(false).ToData()
+	require.NoError(t, err)
+	require.Empty(t, data)
+	require.False(t, called)
+}
+
+type testNVEmbedded struct {
+	Embedded string
+}
+
+type TestExportedEmbedded struct {
+	Exported string
+}
+
+type testNameValuesStruct struct {
+	Name     string `json:"name"`
+	Age      int    `json:"age,omitempty"`
+	Skipped  string `json:"-"`
+	Untagged bool
+	Zero     testZeroer `json:"zero,omitzero"`
+	private  string
+	testNVEmbedded
+	*TestExportedEmbedded
+}
+
+func TestNameValuesFrom(t *testing.T) {
+	nvps, err := NameValuesFrom(map[string]interface{}{"c": 3, "a": 1, "b": []int{2}})
+	require.NoError(t, err)
+	data, err := nvps.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"a":1,"b":[2],"c":3`, string(data))
+
+	nvps, err = NameValuesFrom(map[int]string{10: "ten", 2: "two"})
+	require.NoError(t, err)
+	data, err = nvps.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"10":"ten","2":"two"`, string(data))
+
+	v := testNameValuesStruct{Name: "bilbo", Skipped: "x", Untagged: true, Zero: testZeroer{zero: true}, private: "x", testNVEmbedded: testNVEmbedded{Embedded: "e"}}
+	nvps, err = NameValuesFrom(v)
+	require.NoError(t, err)
+	data, err = nvps.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"name":"bilbo","Untagged":true,"Embedded":"e"`, string(data))
+
+	nvps.Add(NameValue("extra", 1).If(true), NameValue("more", 2).If(false))
+	data, err = nvps.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"name":"bilbo","Untagged":true,"Embedded":"e","extra":1`, string(data))
+
+	v.Age = 111
+	v.Zero.zero = false
+	v.TestExportedEmbedded = &TestExportedEmbedded{Exported: "x"}
+	nvps, err = NameValuesFrom(&v)
+	require.NoError(t, err)
+	data, err = nvps.ToData()
+	require.NoError(t, err)
+	require.Equal(t, `"name":"bilbo","age":111,"Untagged":true,"zero":{},"Embedded":"e","Exported":"x"`, string(data))
+
+	_, err = NameValuesFrom("not a map")
+	require.Error(t, err)
+	require.Equal(t, "cannot create name values from type 'string'", err.Error())
+	_, err = NameValuesFrom(nil)
+	require.Error(t, err)
+	_, err = NameValuesFrom(map[float64]int{})
+	require.Error(t, err)
+	require.Equal(t, "cannot create name values from map with key type 'float64'", err.Error())
+}
+
+type testNVDominant struct {
+	Name  string
+	Other string `json:"other"`
+	Dup   string
+}
+
+type testNVDuplicate struct {
+	Dup string
+}
+
+type testNVFieldRules struct {
+	Name string `json:"name"`
+	*testNVDominant
+	testNVDuplicate
+	Both   int      `json:"both,omitempty,omitzero"`
+	Slice  []string `json:"slice,omitempty,omitzero"`
+	Count  int      `json:"count,string"`
+	Flag   *bool    `json:"flag,string"`
+	Str    string   `json:"str,string"`
+	Values []int    `json:"values,string"`
+}
+
+func TestNameValuesFromFieldRules(t *testing.T) {
+	flag := true
+	v := testNVFieldRules{
+		Name:            "bilbo",
+		testNVDominant:  &testNVDominant{Name: "hidden", Other: "o", Dup: "d1"},
+		testNVDuplicate: testNVDuplicate{Dup: "d2"},
+		Slice:           []string{},
+		Count:           1,
+		Flag:            &flag,
+		Str:             "s",
+		Values:          []int{1},
+	}
+	nvps, err := NameValuesFrom(v)
+	require.NoError(t, err)
+	data, err := nvps.ToData()
+	require.NoError(t, err)
+	expect, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, `"name":"bilbo","Name":"hidden","other":"o","count":"1","flag":"true","str":"\"s\"","values":[1]`, string(data))
+	require.Equal(t, string(expect), "{"+string(data)+"}")
+
+	v.testNVDominant = nil
+	v.Flag = nil
+	nvps, err = NameValuesFrom(&v)
+	require.NoError(t, err)
+	data, err = nvps.ToData()
+	require.NoError(t, err)
+	expect, err = json.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, `"name":"bilbo","count":"1","flag":null,"str":"\"s\"","values":[1]`, string(data))
+	require.Equal(t, string(expect), "{"+string(data)+"}")
+}
+
+type testZeroer struct {
+	zero bool
+}
+
+func (z testZeroer) IsZero() bool {
+	return z.zero
+}
+
+func TestNameValueOmitModes(t *testing.T) {
+	var nilPtr *int
+	var nilZeroer *testZeroer
+	one := 1
+	testCases := []struct {
+		value          interface{}
+		expectEmpty    bool
+		expectZero     bool
+		expectJSONZero bool
+	}{
+		{nil, true, true, true},
+		{nilPtr, false, true, true},
+		{&one, false, false, false},
+		{"", false, true, true},
+		{"a", false, false, false},
+		{0, false, true, true},
+		{0.0, false, true, true},
+		{uint8(0), false, true, true},
+		{1, false, false, false},
+		{false, false, true, true},
+		{true, false, false, false},
+		{[]string{}, false, false, true},
+		{[]string(nil), false, true, true},
+		{map[string]interface{}{}, false, false, true},
+		{[0]int{}, false, true, true},
+		{[2]int{}, false, true, false},
+		{struct{}{}, false, true, false},
+		{testZeroer{zero: true}, false, true, false},
+		{testZeroer{zero: false}, false, false, false},
+		{nilZeroer, false, true, true},
+		{func(name string) interface{} { return "" }, false, true, true},
+		{func(name string) interface{} { return nil }, true, true, true},
+	}
+	for i, tc := range testCases {
+		data, err := NameValue("foo", tc.value).OmitEmpty().ToData()
+		require.NoError(t, err)
+		require.Equal(t, tc.expectEmpty, len(data) == 0, "test case %d (OmitEmpty)", i)
+		data, err = NameValue("foo", tc.value).OmitZero().ToData()
+		require.NoError(t, err)
+		require.Equal(t, tc.expectZero, len(data) == 0, "test case %d (OmitZero)", i)
+		data, err = NameValue("foo", tc.value).OmitEmptyJSON().ToData()
+		require.NoError(t, err)
+		require.Equal(t, tc.expectJSONZero, len(data) == 0, "test case %d (OmitEmptyJSON)", i)
+	}
+
+	jt := MustCompileTemplate(`{?}`)
+	str, err := jt.String(NameValues(NameValue("a", "").OmitEmptyJSON(), NameValue("b", 0).OmitZero(), NameValue("c", "x").OmitEmptyJSON()))
+	require.NoError(t, err)
+	require.Equal(t, `{"c":"x"}`, str)
+}
+
 type counter struct {
 	current int
 }
@@ -229,3 +502,34 @@ func TestNameValuesMarshallError(t *testing.T) {
 	_, err = jt.String(nvps)
 	require.Error(t, err)
 }
+
+type testDataProvider struct {
+	data []byte
+	err  error
+}
+
+func (p *testDataProvider) JSONTData() ([]byte, error) {
+	return p.data, p.err
+}
+
+func TestDataProvider(t *testing.T) {
+	var _ DataProvider = NameValue("foo", 1)
+	var _ DataProvider = NameValues()
+
+	jt, err := NewTemplate(`{"foo":?,?}`)
+	require.NoError(t, err)
+	str, err := jt.String(&testDataProvider{data: []byte(`[1,2]`)}, NameValues(NameValue("bar", &testDataProvider{data: []byte(`{"a":true}`)})))
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":[1,2],"bar":{"a":true}}`, str)
+
+	_, err = jt.String(&testDataProvider{err: errors.New("fooey")}, nil)
+	require.Error(t, err)
+	require.Equal(t, "arg[0] at line 1, column 8: fooey", err.Error())
+
+	jt, err = NewTemplate(`{"foo":?}`, OptionValidateRawArgs)
+	require.NoError(t, err)
+	_, err = jt.String(&testDataProvider{data: []byte(`[1,2`)})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrInvalidJSON))
+	require.Equal(t, "arg[0] at line 1, column 8: invalid JSON data provider value: unexpected end of JSON input", err.Error())
+}
@@ -0,0 +1,112 @@
+package jsont
+
+import (
+	"context"
+)
+
+// encodedDefault is a default arg value that has been pre-encoded (when the default was set) - so that
+// the default value is not re-marshalled on every render
+//
+// The original value is retained - for use when the arg marker has filters (filters must receive the raw value)
+type encodedDefault struct {
+	value interface{}
+	data  []byte
+}
+
+type lazyDefault struct {
+	value interface{}
+}
+
+// dynamicDefault is a default arg value that is a func - called each time the default is used (i.e. at render time)
+type dynamicDefault struct {
+	argName string
+	fn      func(name string) (interface{}, error)
+}
+
+func (d *dynamicDefault) value() (interface{}, error) {
+	return d.fn(d.argName)
+}
+
+// LazyDefault wraps a default arg value so that it is not pre-encoded when the default is set (see
+// NamedTemplate.DefaultArgValue) - the value is instead marshalled each time it is used
+//
+// Use this for default values whose JSON may change after the default is set (e.g. a pointer to a struct that is later updated)
+//
+// Note: LazyDefault is only for use with default arg values
+func LazyDefault(value interface{}) interface{} {
+	return lazyDefault{value: value}
+}
+
+// encodeDefault pre-encodes a default arg value (in the same way as arg values are encoded) - values that provide
+// their own data (DataProvider, which may differ on each render), are lazy or cannot be marshalled are not pre-encoded
+//
+// Default values that are a func() interface{} or func(name string) (interface{}, error) are dynamic - the
+// func is called each time the default is used
+func encodeDefault(argName string, value interface{}) interface{} {
+	switch vt := value.(type) {
+	case DataProvider, func(context.Context) (interface{}, error):
+		return value
+	case lazyDefault:
+		if dd, ok := encodeDefault(argName, vt.value).(*dynamicDefault); ok {
+			return dd
+		}
+		return vt.value
+	case func() interface{}:
+		return &dynamicDefault{argName: argName, fn: func(string) (interface{}, error) {
+			return vt(), nil
+		}}
+	case func(string) (interface{}, error):
+		return &dynamicDefault{argName: argName, fn: vt}
+	}
+	if data, err := argValueToData(context.Background(), value); err == nil {
+		return &encodedDefault{value: value, data: data}
+	}
+	return value
+}
+
+// rawDefault returns the original value of a (possibly pre-encoded) default arg value
+func rawDefault(dv interface{}) interface{} {
+	if ed, ok := dv.(*encodedDefault); ok {
+		return ed.value
+	}
+	return dv
+}
+
+func (t *jsonNamedTemplate) setDefaultArgValue(argName string, value interface{}) {
+	t.defaultArgValues[argName] = encodeDefault(argName, value)
+}
+
+// encodedDefaultData returns the data for a pre-encoded default of a named arg token - the pre-encoded data is used
+// unless the token has filters (which must receive the original value) and is checked in the same way as other arg
+// values (i.e. when raw arg values are validated)
+func (t *jsonNamedTemplate) encodedDefaultData(ctx context.Context, tkn jsonTemplateToken, ed *encodedDefault) ([]byte, error) {
+	if len(tkn.filters) > 0 {
+		return t.namedArgData(ctx, tkn, ed.value)
+	} else if t.validation == validateRawArgs {
+		if err := checkRawArgData(ed.value, ed.data); err != nil {
+			return nil, newNamedArgMarshalError(tkn, err)
+		}
+	}
+	return ed.data, nil
+}
+
+// bakeableDefault returns the pre-encoded default for a named arg token (i.e. the default that would be used
+// if none of the token's arg names were supplied) - tokens for env args and tokens whose default is not
+// pre-encoded cannot be baked
+func (t *jsonNamedTemplate) bakeableDefault(tkn jsonTemplateToken) (*encodedDefault, bool) {
+	names := tkn.chainNames()
+	if t.envArgs {
+		for _, argName := range names {
+			if isEnvArgName(argName) {
+				return nil, false
+			}
+		}
+	}
+	for _, argName := range names {
+		if dv, ok := t.defaultArgValues[argName]; ok {
+			ed, ok := dv.(*encodedDefault)
+			return ed, ok
+		}
+	}
+	return nil, false
+}
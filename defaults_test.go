@@ -0,0 +1,211 @@
+package jsont
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDefault(t *testing.T) {
+	ed, ok := encodeDefault("a", map[string]interface{}{"a": 1}).(*encodedDefault)
+	require.True(t, ok)
+	require.Equal(t, `{"a":1}`, string(ed.data))
+	require.Equal(t, map[string]interface{}{"a": 1}, ed.value)
+
+	lazyFn := func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}
+	encoded := []struct {
+		value  interface{}
+		expect string
+	}{
+		{nil, `null`},
+		{[]byte(`1`), `1`},
+		{json.RawMessage(`[2]`), `[2]`},
+	}
+	for i, tc := range encoded {
+		ed, ok = encodeDefault("a", tc.value).(*encodedDefault)
+		require.True(t, ok, "test case %d", i)
+		require.Equal(t, tc.expect, string(ed.data), "test case %d", i)
+	}
+	notEncoded := []interface{}{
+		NameValue("a", 1),
+		func() {},
+	}
+	for i, v := range notEncoded {
+		_, ok = encodeDefault("a", v).(*encodedDefault)
+		require.False(t, ok, "test case %d", i)
+	}
+	_, ok = encodeDefault("a", lazyFn).(func(context.Context) (interface{}, error))
+	require.True(t, ok)
+	require.Equal(t, 1, encodeDefault("a", LazyDefault(1)))
+	require.Equal(t, 1, rawDefault(encodeDefault("a", 1)))
+	require.Equal(t, 1, rawDefault(1))
+}
+
+type testMutableDefault struct {
+	Value string
+}
+
+func TestPreEncodedDefaults(t *testing.T) {
+	mutable := &testMutableDefault{Value: "a"}
+	lazy := &testMutableDefault{Value: "a"}
+	jt := MustCompileNamedTemplate(`{"enc":?enc,"lazy":?lazy,"filtered":?name|upper,"chained":?x?:name}`,
+		OptionDefaultArgValues(map[string]interface{}{"enc": mutable, "lazy": LazyDefault(lazy), "name": "bilbo"}))
+	str, err := jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"enc":{"Value":"a"},"lazy":{"Value":"a"},"filtered":"BILBO","chained":"bilbo"}`, str)
+
+	// pre-encoded default is not affected by changes - lazy default is...
+	mutable.Value = "b"
+	lazy.Value = "b"
+	str, err = jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"enc":{"Value":"a"},"lazy":{"Value":"b"},"filtered":"BILBO","chained":"bilbo"}`, str)
+
+	// defaults are only pre-encoded when set...
+	str, err = jt.DefaultArgValue("enc", mutable).String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"enc":{"Value":"b"},"lazy":{"Value":"b"},"filtered":"BILBO","chained":"bilbo"}`, str)
+
+	// schema uses the raw default...
+	schema, err := jt.Schema()
+	require.NoError(t, err)
+	props := schema["properties"].(map[string]interface{})
+	require.Equal(t, "BILBO", props["filtered"].(map[string]interface{})["default"])
+	require.Equal(t, "bilbo", props["chained"].(map[string]interface{})["default"])
+}
+
+func TestPreEncodedDefaultsOutputValidation(t *testing.T) {
+	jt := MustCompileNamedTemplate(`{"a":?a,?b}`, OptionValidateOutput, OptionDefaultArgValue("a", 1))
+	_, err := jt.String(map[string]interface{}{"b": NameValues()})
+	require.Error(t, err)
+	require.Equal(t, "invalid JSON output at offset 7 (after named arg 'b' at line 1, column 9): invalid character '}' looking for beginning of object key string", err.Error())
+	_, err = NewNamedTemplate(`[?a,?b]`, OptionValidateOutput, OptionDefaultArgValue("a", 1))
+	require.NoError(t, err)
+}
+
+func TestPreEncodedDefaultsRawArgsValidation(t *testing.T) {
+	jt := MustCompileNamedTemplate(`{"a":?a}`, OptionDefaultArgValue("a", json.RawMessage(`{"a":`)))
+	str, err := jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"a":}`, str)
+
+	// validation applies regardless of the order options are applied...
+	jt = jt.Options(OptionValidateRawArgs)
+	_, err = jt.String(nil)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrInvalidJSON))
+	require.True(t, strings.HasPrefix(err.Error(), "named arg 'a' at line 1, column 6: "))
+	_, err = jt.NewWithDefaults(nil)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrInvalidJSON))
+
+	jt = jt.DefaultArgValue("a", json.RawMessage(`{"a":1}`))
+	str, err = jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"a":1}}`, str)
+	jt2, err := jt.NewWithDefaults(nil)
+	require.NoError(t, err)
+	str, err = jt2.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"a":1}}`, str)
+}
+
+func TestNamedTemplateNewWithDefaults(t *testing.T) {
+	jt := MustCompileNamedTemplate(`{"a":?a,"b":?b,"c":?c|upper,"d":?d?:e,"f":?f,"env":?env.JSONT_TEST_ENV}`,
+		OptionEnvArgs,
+		OptionDefaultArgValues(map[string]interface{}{
+			"a":                  1,
+			"c":                  "ccc",
+			"e":                  "eee",
+			"f":                  LazyDefault("fff"),
+			"env.JSONT_TEST_ENV": "env default",
+		}))
+	jt2, err := jt.NewWithDefaults(map[string]interface{}{"b": 2})
+	require.NoError(t, err)
+	njt := jt2.(*jsonNamedTemplate)
+	require.Equal(t, 5, len(njt.tokens))
+	require.Equal(t, `{"a":1,"b":2,"c":"CCC","d":"eee","f":`, string(njt.tokens[0].fixedValue))
+	require.Equal(t, map[string]bool{"f": true, "env.JSONT_TEST_ENV": true}, jt2.ExpectedArgs())
+	str, err := jt2.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":2,"c":"CCC","d":"eee","f":"fff","env":"env default"}`, str)
+	// baked defaults can no longer be overridden...
+	str, err = jt2.String(map[string]interface{}{"a": "x", "f": "y"})
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":2,"c":"CCC","d":"eee","f":"y","env":"env default"}`, str)
+
+	// NewWith does not use defaults...
+	jt2, err = jt.NewWith(map[string]interface{}{"b": 2})
+	require.NoError(t, err)
+	require.Equal(t, 6, len(jt2.ExpectedArgs()))
+
+	jt = MustCompileNamedTemplate(`{"a":?a|trunc:x}`, OptionDefaultArgValue("a", "aaa"))
+	_, err = jt.NewWithDefaults(nil)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "named arg 'a' at line 1, column 6: "))
+}
+
+func TestDynamicDefaults(t *testing.T) {
+	calls := 0
+	jt := MustCompileNamedTemplate(`{"counter":?counter,"name":?name|upper,"chained":?x?:y,"lazy":?lazy}`,
+		OptionDefaultArgValue("counter", func() interface{} {
+			calls++
+			return calls
+		}),
+		OptionDefaultArgValues(map[string]interface{}{
+			"name": func(name string) (interface{}, error) {
+				return "default " + name, nil
+			},
+			"y": func(name string) (interface{}, error) {
+				return name, nil
+			},
+			"lazy": LazyDefault(func() interface{} {
+				return "lazy"
+			}),
+		}))
+	str, err := jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"counter":1,"name":"DEFAULT NAME","chained":"y","lazy":"lazy"}`, str)
+	str, err = jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"counter":2,"name":"DEFAULT NAME","chained":"y","lazy":"lazy"}`, str)
+	// not called when arg supplied...
+	str, err = jt.String(map[string]interface{}{"counter": 0, "name": "x", "x": "x", "lazy": nil})
+	require.NoError(t, err)
+	require.Equal(t, `{"counter":0,"name":"X","chained":"x","lazy":null}`, str)
+	require.Equal(t, 2, calls)
+
+	// dynamic defaults are not baked or used in schema...
+	jt2, err := jt.NewWithDefaults(nil)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(jt2.ExpectedArgs()))
+	str, err = jt2.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"counter":3,"name":"DEFAULT NAME","chained":"y","lazy":"lazy"}`, str)
+	schema, err := jt.Schema()
+	require.NoError(t, err)
+	props := schema["properties"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{}, props["counter"])
+	require.Equal(t, map[string]interface{}{"type": []string{"string", "null"}}, props["name"])
+
+	// error from dynamic default...
+	jt = MustCompileNamedTemplate(`{"a":?a}`).DefaultArgValue("a", func(name string) (interface{}, error) {
+		return nil, errors.New("no default for " + name)
+	})
+	_, err = jt.String(nil)
+	require.Error(t, err)
+	require.Equal(t, "named arg 'a' at line 1, column 6: no default for a", err.Error())
+
+	// mixed template...
+	mjt := MustCompileMixedTemplate(`[?a,?]`).DefaultArgValue("a", func() interface{} {
+		return "dynamic"
+	})
+	str, err = mjt.String(nil, 1)
+	require.NoError(t, err)
+	require.Equal(t, `["dynamic",1]`, str)
+}
@@ -21,5 +21,13 @@ And then generate JSON from the template by supplying named args:
 would produce:
   {"foo":"aaa","bar":true,"baz":"?","qux":1.2}
 
+Mixed templates (with both positional and named args) can also be created and used:
+  jsonTemplate, _ := jsont.NewMixedTemplate(`{"foo":?foo,"bar":?,"baz":"??","qux":?}`)
+And then generate JSON from the template by supplying named args and positional args:
+  str, _ := jsonTemplate.String(map[string]interface{}{"foo":"aaa"}, true, 1.2)
+  println(str)
+would produce:
+  {"foo":"aaa","bar":true,"baz":"?","qux":1.2}
+
 */
 package jsont
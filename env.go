@@ -0,0 +1,72 @@
+package jsont
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EnvArgType is a type coercion hint for environment variable named args (see OptionEnvArgTypes)
+type EnvArgType int
+
+const (
+	// EnvString the environment variable value is used as a string (the default)
+	EnvString EnvArgType = iota
+	// EnvNumber the environment variable value is coerced to a JSON number
+	EnvNumber
+	// EnvInt the environment variable value is coerced to an integer
+	EnvInt
+	// EnvBool the environment variable value is coerced to a boolean
+	EnvBool
+)
+
+func (e EnvArgType) String() string {
+	switch e {
+	case EnvNumber:
+		return "number"
+	case EnvInt:
+		return "int"
+	case EnvBool:
+		return "bool"
+	}
+	return "string"
+}
+
+// envArgPrefix is the named arg prefix used for environment variable args (when enabled by OptionEnvArgs)
+const envArgPrefix = "env."
+
+// jsonNumberRegex matches a valid JSON number (strconv.ParseFloat also accepts values such as NaN, Inf, +1 and hex
+// floats - which are not valid JSON numbers)
+var jsonNumberRegex = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+func isEnvArgName(argName string) bool {
+	return len(argName) > len(envArgPrefix) && strings.HasPrefix(argName, envArgPrefix)
+}
+
+func lookupEnvArg(argName string, types map[string]EnvArgType) (interface{}, bool, error) {
+	envName := argName[len(envArgPrefix):]
+	v, ok := os.LookupEnv(envName)
+	if !ok {
+		return nil, false, nil
+	}
+	switch typ := types[envName]; typ {
+	case EnvNumber:
+		if jsonNumberRegex.MatchString(v) {
+			return json.Number(v), true, nil
+		}
+	case EnvInt:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, true, nil
+		}
+	case EnvBool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, true, nil
+		}
+	default:
+		return v, true, nil
+	}
+	return nil, false, fmt.Errorf("environment variable '%s' value %q cannot be coerced to %s", envName, v, types[envName])
+}
@@ -0,0 +1,98 @@
+package jsont
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNamedTemplateEnvArgs(t *testing.T) {
+	t.Setenv("JSONT_TEST_URL", "postgres://localhost")
+	t.Setenv("JSONT_TEST_PORT", "5432")
+	t.Setenv("JSONT_TEST_RATIO", "0.5")
+	t.Setenv("JSONT_TEST_DEBUG", "true")
+	jt, err := NewNamedTemplate(`{"url":?env.JSONT_TEST_URL,"port":?env.JSONT_TEST_PORT,"ratio":?env.JSONT_TEST_RATIO,"debug":?env.JSONT_TEST_DEBUG}`,
+		OptionEnvArgs, OptionEnvArgTypes(map[string]EnvArgType{"JSONT_TEST_PORT": EnvInt, "JSONT_TEST_RATIO": EnvNumber}), OptionEnvArgTypes(map[string]EnvArgType{"JSONT_TEST_DEBUG": EnvBool}))
+	require.NoError(t, err)
+	require.Equal(t, 3, len((jt.(*jsonNamedTemplate)).envArgTypes))
+
+	str, err := jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"url":"postgres://localhost","port":5432,"ratio":0.5,"debug":true}`, str)
+
+	t.Setenv("JSONT_TEST_PORT", "not a number")
+	_, err = jt.String(nil)
+	require.Error(t, err)
+	require.Equal(t, `named arg 'env.JSONT_TEST_PORT' at line 1, column 35: environment variable 'JSONT_TEST_PORT' value "not a number" cannot be coerced to int`, err.Error())
+	t.Setenv("JSONT_TEST_PORT", "5432")
+	for _, v := range []string{"x", "NaN", "Inf", "+1", "0x1p-2", "01", "1.", ".5", "1_000"} {
+		t.Setenv("JSONT_TEST_RATIO", v)
+		_, err = jt.String(nil)
+		require.Error(t, err, "value %q", v)
+	}
+	for _, v := range []string{"0", "-1", "1.5e10", "1E-3", "-0.25"} {
+		t.Setenv("JSONT_TEST_RATIO", v)
+		str, err = jt.String(nil)
+		require.NoError(t, err, "value %q", v)
+		require.Equal(t, `{"url":"postgres://localhost","port":5432,"ratio":`+v+`,"debug":true}`, str)
+	}
+	t.Setenv("JSONT_TEST_RATIO", "0.5")
+	t.Setenv("JSONT_TEST_DEBUG", "x")
+	_, err = jt.String(nil)
+	require.Error(t, err)
+}
+
+func TestNamedTemplateEnvArgsMissing(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"url":?env.JSONT_TEST_MISSING}`, OptionEnvArgs)
+	require.NoError(t, err)
+
+	_, err = jt.String(nil)
+	require.Error(t, err)
+	require.Equal(t, "expected environment variable 'JSONT_TEST_MISSING' for named arg 'env.JSONT_TEST_MISSING'", err.Error())
+
+	// env args are not taken from supplied args...
+	_, err = jt.String(map[string]interface{}{"env.JSONT_TEST_MISSING": "x"})
+	require.Error(t, err)
+
+	jt = jt.DefaultArgValue("env.JSONT_TEST_MISSING", "default")
+	str, err := jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"url":"default"}`, str)
+
+	jt, err = NewNamedTemplate(`{"url":?env.JSONT_TEST_MISSING}`, OptionEnvArgs, OptionNonStrict)
+	require.NoError(t, err)
+	str, err = jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"url":null}`, str)
+}
+
+func TestNamedTemplateEnvArgsNotEnabled(t *testing.T) {
+	t.Setenv("JSONT_TEST_URL", "postgres://localhost")
+	jt, err := NewNamedTemplate(`{"url":?env.JSONT_TEST_URL}`)
+	require.NoError(t, err)
+
+	str, err := jt.String(map[string]interface{}{"env": map[string]interface{}{"JSONT_TEST_URL": "from args"}})
+	require.NoError(t, err)
+	require.Equal(t, `{"url":"from args"}`, str)
+}
+
+func TestMixedTemplateEnvArgs(t *testing.T) {
+	t.Setenv("JSONT_TEST_URL", "postgres://localhost")
+	jt, err := NewMixedTemplate(`{"url":?env.JSONT_TEST_URL,"foo":?}`, OptionEnvArgs)
+	require.NoError(t, err)
+	str, err := jt.String(nil, "aaa")
+	require.NoError(t, err)
+	require.Equal(t, `{"url":"postgres://localhost","foo":"aaa"}`, str)
+}
+
+func TestOptionEnvArgsErrors(t *testing.T) {
+	_, err := NewTemplate(`{"foo":?}`, OptionEnvArgs)
+	require.Error(t, err)
+	require.Equal(t, "option OptionEnvArgs cannot be applied to type '*jsont.jsonTemplate'", err.Error())
+}
+
+func TestEnvArgType_String(t *testing.T) {
+	require.Equal(t, "string", EnvString.String())
+	require.Equal(t, "number", EnvNumber.String())
+	require.Equal(t, "int", EnvInt.String())
+	require.Equal(t, "bool", EnvBool.String())
+}
@@ -0,0 +1,406 @@
+package jsont
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrMissingArg is the error (tested using errors.Is) for named args that are missing when rendering a template
+	ErrMissingArg = errors.New("missing arg")
+	// ErrArgCount is the error (tested using errors.Is) for an incorrect number of positional args
+	ErrArgCount = errors.New("incorrect number of args")
+	// ErrUnknownArg is the error (tested using errors.Is) for supplied named args that are not expected
+	// by the template (when using OptionRejectUnknownArgs)
+	ErrUnknownArg = errors.New("unknown arg")
+	// ErrSchemaValidation is the error (tested using errors.Is) for rendered output that does not validate
+	// against the template schema (when using OptionSchema)
+	ErrSchemaValidation = errors.New("schema validation failed")
+	// ErrInvalidJSON is the error (tested using errors.Is) for rendered output or raw arg values that are not
+	// valid JSON (when using OptionValidateOutput or OptionValidateRawArgs)
+	ErrInvalidJSON = errors.New("invalid JSON")
+	// ErrDuplicateName is the error (tested using errors.Is) for name value pairs that have duplicate names
+	// (when using NameValuePairs.CheckDuplicates)
+	ErrDuplicateName = errors.New("duplicate name")
+)
+
+// Position is a position within a template string
+type Position struct {
+	// Offset is the byte offset (0 based)
+	Offset int
+	// Line is the line number (1 based)
+	Line int
+	// Column is the byte column within the line (1 based)
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+// MissingArgError is the error returned when a named arg is missing (and has no default) when rendering a template
+//
+// errors.Is(err, ErrMissingArg) returns true for this error
+type MissingArgError struct {
+	// ArgName is the name of the missing named arg
+	ArgName string
+	// Fallbacks is any fallback arg names (that were also missing) for the named arg marker
+	Fallbacks []string
+	// Position is the position of the named arg marker in the template
+	Position Position
+	message  string
+}
+
+func (e *MissingArgError) Error() string {
+	return e.message
+}
+
+// Is reports whether the target is ErrMissingArg
+func (e *MissingArgError) Is(target error) bool {
+	return target == ErrMissingArg
+}
+
+// ArgCountError is the error returned when the number of positional args supplied is incorrect
+//
+// errors.Is(err, ErrArgCount) returns true for this error
+type ArgCountError struct {
+	// Expected is the expected number of args (or maximum number of args)
+	Expected int
+	// Supplied is the number of args supplied
+	Supplied int
+	message  string
+}
+
+func (e *ArgCountError) Error() string {
+	return e.message
+}
+
+// Is reports whether the target is ErrArgCount
+func (e *ArgCountError) Is(target error) bool {
+	return target == ErrArgCount
+}
+
+// ArgMarshalError is the error returned when an arg value cannot be converted to JSON
+//
+// The wrapped error (see Unwrap) is the underlying marshalling, filter or coercion error
+type ArgMarshalError struct {
+	// ArgName is the name of the arg (empty for positional args)
+	ArgName string
+	// ArgIndex is the (0 based) index of the arg (-1 for named args)
+	ArgIndex int
+	// Position is the position of the arg marker in the template
+	Position Position
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ArgMarshalError) Error() string {
+	if e.Position.Line == 0 {
+		// no position (e.g. the arg has no marker in the template)...
+		if e.ArgIndex < 0 {
+			return fmt.Sprintf("named arg '%s': %s", e.ArgName, e.Err.Error())
+		}
+		return fmt.Sprintf("arg[%d]: %s", e.ArgIndex, e.Err.Error())
+	} else if e.ArgIndex < 0 {
+		return fmt.Sprintf("named arg '%s' at %s: %s", e.ArgName, e.Position, e.Err.Error())
+	}
+	return fmt.Sprintf("arg[%d] at %s: %s", e.ArgIndex, e.Position, e.Err.Error())
+}
+
+// Unwrap returns the underlying error
+func (e *ArgMarshalError) Unwrap() error {
+	return e.Err
+}
+
+// FilterError is the error (wrapped by ArgMarshalError) when a filter on a named arg marker fails
+type FilterError struct {
+	// Filter is the name of the filter
+	Filter string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("filter '%s': %s", e.Filter, e.Err.Error())
+}
+
+// Unwrap returns the underlying error
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaError is the error returned when the rendered output of a template does not validate against
+// the schema (see OptionSchema)
+//
+// errors.Is(err, ErrSchemaValidation) returns true for this error
+type SchemaError struct {
+	// Pointer is the JSON Pointer (RFC 6901) of the location in the rendered output that failed validation
+	Pointer string
+	// Keyword is the schema keyword that failed validation (e.g. "type", "required")
+	Keyword string
+	message string
+}
+
+func (e *SchemaError) Error() string {
+	return e.message
+}
+
+// Is reports whether the target is ErrSchemaValidation
+func (e *SchemaError) Is(target error) bool {
+	return target == ErrSchemaValidation
+}
+
+// InvalidOutputError is the error returned when the rendered output of a template is not valid JSON (see OptionValidateOutput)
+//
+// errors.Is(err, ErrInvalidJSON) returns true for this error
+type InvalidOutputError struct {
+	// ArgName is the name of the arg rendered before the invalid output (empty for positional args)
+	ArgName string
+	// ArgIndex is the (0 based) index of the positional arg rendered before the invalid output (-1 for named args
+	// or when no arg was rendered before the invalid output)
+	ArgIndex int
+	// Position is the position in the template of the arg marker rendered before the invalid output
+	Position Position
+	// Offset is the byte offset in the rendered output of the invalid JSON
+	Offset int
+	// Err is the underlying JSON syntax error
+	Err error
+	arg bool
+}
+
+func (e *InvalidOutputError) Error() string {
+	if !e.arg {
+		return fmt.Sprintf("invalid JSON output at offset %d: %s", e.Offset, e.Err.Error())
+	} else if e.ArgIndex < 0 {
+		return fmt.Sprintf("invalid JSON output at offset %d (after named arg '%s' at %s): %s", e.Offset, e.ArgName, e.Position, e.Err.Error())
+	}
+	return fmt.Sprintf("invalid JSON output at offset %d (after arg[%d] at %s): %s", e.Offset, e.ArgIndex, e.Position, e.Err.Error())
+}
+
+// Is reports whether the target is ErrInvalidJSON
+func (e *InvalidOutputError) Is(target error) bool {
+	return target == ErrInvalidJSON
+}
+
+// Unwrap returns the underlying error
+func (e *InvalidOutputError) Unwrap() error {
+	return e.Err
+}
+
+// TemplateSyntaxError is the error returned when a template string cannot be compiled
+type TemplateSyntaxError struct {
+	// Position is the position in the template of the syntax error
+	Position Position
+	// Err is any underlying error (e.g. the JSON syntax error when using OptionChecked)
+	Err     error
+	message string
+}
+
+func (e *TemplateSyntaxError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the underlying error
+func (e *TemplateSyntaxError) Unwrap() error {
+	return e.Err
+}
+
+func newMissingArgError(tkn jsonTemplateToken, format string, a ...any) error {
+	return &MissingArgError{
+		ArgName:   tkn.argName,
+		Fallbacks: copyStrings(tkn.fallbacks),
+		Position:  tkn.pos,
+		message:   fmt.Sprintf(format, a...),
+	}
+}
+
+func newArgCountError(expected, supplied int, format string) error {
+	return &ArgCountError{
+		Expected: expected,
+		Supplied: supplied,
+		message:  fmt.Sprintf(format, expected, supplied),
+	}
+}
+
+func newNamedArgMarshalError(tkn jsonTemplateToken, err error) error {
+	return &ArgMarshalError{
+		ArgName:  tkn.argName,
+		ArgIndex: -1,
+		Position: tkn.pos,
+		Err:      err,
+	}
+}
+
+func newSyntaxError(data []byte, offset int, err error, format string, a ...any) error {
+	return &TemplateSyntaxError{
+		Position: positionOf(data, offset),
+		Err:      err,
+		message:  fmt.Sprintf(format, a...),
+	}
+}
+
+// positionOf determines the line and column of a byte offset within the template data
+func positionOf(data []byte, offset int) Position {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	return Position{Line: 1, Column: 1}.advance(data[:offset])
+}
+
+// setPositions sets the line and column positions of tokens (whose offsets have been set during parsing)
+func (t tokens) setPositions(data []byte) {
+	pos := Position{Line: 1, Column: 1}
+	for i := range t {
+		if offset := t[i].pos.Offset; offset > pos.Offset && offset <= len(data) {
+			pos = pos.advance(data[pos.Offset:offset])
+		}
+		t[i].pos = pos
+	}
+}
+
+// argIndexPosition returns the position of the first marker for a positional arg index (or no position, i.e. line 0,
+// if there is no marker for the arg index)
+func (t tokens) argIndexPosition(argIndex int) Position {
+	for _, tkn := range t {
+		if !tkn.fixed && tkn.argName == "" && tkn.argIndex == argIndex {
+			return tkn.pos
+		}
+	}
+	return Position{}
+}
+
+// checkError converts the error from checking a template (rendered with null args) is valid JSON to a TemplateSyntaxError
+func (t tokens) checkError(err error) error {
+	offset := 0
+	if se, ok := err.(*json.SyntaxError); ok && se.Offset > 0 {
+		offset = int(se.Offset) - 1
+	}
+	pos := Position{Line: 1, Column: 1}
+	rendered := 0
+	for _, tkn := range t {
+		l := nullDataLen
+		if tkn.fixed {
+			l = len(tkn.fixedValue)
+		}
+		pos = tkn.pos
+		if offset < rendered+l {
+			if tkn.fixed {
+				pos = pos.advanceFixed(tkn.fixedValue[:offset-rendered])
+			}
+			break
+		} else if tkn.fixed {
+			pos = pos.advanceFixed(tkn.fixedValue)
+		}
+		rendered += l
+	}
+	return &TemplateSyntaxError{
+		Position: pos,
+		Err:      err,
+		message:  fmt.Sprintf("invalid JSON template at %s: %s", pos, err.Error()),
+	}
+}
+
+// advanceFixed advances the position over (part of) the fixed value of a parsed token - where each '?' in a parsed
+// fixed value is from a '??' escape (and so is two bytes in the template)
+func (p Position) advanceFixed(fixedValue []byte) Position {
+	for _, b := range fixedValue {
+		if b == '?' {
+			p.Offset++
+			p.Column++
+		}
+	}
+	return p.advance(fixedValue)
+}
+
+func (p Position) advance(data []byte) Position {
+	for _, b := range data {
+		p.Offset++
+		if b == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
+
+// ArgErrors is the error returned when rendering a template (with OptionCollectErrors) - listing all the
+// missing and invalid args from the render attempt
+//
+// errors.Is and errors.As test against each of the listed errors
+type ArgErrors struct {
+	// Errors is the list of arg errors (in template order)
+	Errors []error
+}
+
+func (e *ArgErrors) Error() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%d arg errors:", len(e.Errors)))
+	for i, err := range e.Errors {
+		if i > 0 {
+			builder.WriteByte(';')
+		}
+		builder.WriteByte(' ')
+		builder.WriteString(err.Error())
+	}
+	return builder.String()
+}
+
+// Unwrap returns the listed errors
+func (e *ArgErrors) Unwrap() []error {
+	return e.Errors
+}
+
+// Is reports whether any of the listed errors matches the target
+func (e *ArgErrors) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first listed error that matches the target
+func (e *ArgErrors) As(target any) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// argErrorsCollector collects arg errors during rendering (when OptionCollectErrors is used) - a nil
+// collector indicates errors are not being collected
+type argErrorsCollector struct {
+	errs   []error
+	failed map[string]bool
+}
+
+func newArgErrorsCollector(collect bool) *argErrorsCollector {
+	if collect {
+		return &argErrorsCollector{
+			failed: map[string]bool{},
+		}
+	}
+	return nil
+}
+
+// add adds an error for the arg (only the first error for each arg is kept)
+func (c *argErrorsCollector) add(arg string, err error) {
+	if !c.failed[arg] {
+		c.failed[arg] = true
+		c.errs = append(c.errs, err)
+	}
+}
+
+func (c *argErrorsCollector) error() error {
+	if c != nil && len(c.errs) > 0 {
+		return &ArgErrors{Errors: c.errs}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package jsont
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMissingArgError(t *testing.T) {
+	jt, err := NewNamedTemplate("{\n  \"foo\": ?foo,\n  \"bar\": ?bar?:baz\n}")
+	require.NoError(t, err)
+
+	_, err = jt.String(map[string]interface{}{"bar": 1})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrMissingArg))
+	require.False(t, errors.Is(err, ErrArgCount))
+	var mae *MissingArgError
+	require.True(t, errors.As(err, &mae))
+	require.Equal(t, "foo", mae.ArgName)
+	require.Empty(t, mae.Fallbacks)
+	require.Equal(t, Position{Offset: 11, Line: 2, Column: 10}, mae.Position)
+	require.Equal(t, "expected named arg 'foo'", err.Error())
+
+	_, err = jt.String(map[string]interface{}{"foo": 1})
+	require.True(t, errors.Is(err, ErrMissingArg))
+	require.True(t, errors.As(err, &mae))
+	require.Equal(t, "bar", mae.ArgName)
+	require.Equal(t, []string{"baz"}, mae.Fallbacks)
+	require.Equal(t, Position{Offset: 26, Line: 3, Column: 10}, mae.Position)
+	require.Equal(t, "line 3, column 10", mae.Position.String())
+}
+
+func TestMissingArgErrorPaths(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"foo":?foo.bar,"env":?env.JSONT_TEST_MISSING}`, OptionEnvArgs)
+	require.NoError(t, err)
+
+	_, err = jt.String(map[string]interface{}{"foo": map[string]interface{}{}})
+	require.True(t, errors.Is(err, ErrMissingArg))
+	require.Equal(t, "named arg 'foo.bar' - path segment 'foo.bar' not found", err.Error())
+
+	_, err = jt.String(map[string]interface{}{"foo": map[string]interface{}{"bar": 1}})
+	require.True(t, errors.Is(err, ErrMissingArg))
+	var mae *MissingArgError
+	require.True(t, errors.As(err, &mae))
+	require.Equal(t, "env.JSONT_TEST_MISSING", mae.ArgName)
+	require.Equal(t, 22, mae.Position.Offset)
+}
+
+func TestArgCountError(t *testing.T) {
+	jt, err := NewTemplate(`{"foo":?,"bar":?}`)
+	require.NoError(t, err)
+
+	_, err = jt.String(1)
+	require.True(t, errors.Is(err, ErrArgCount))
+	var ace *ArgCountError
+	require.True(t, errors.As(err, &ace))
+	require.Equal(t, 2, ace.Expected)
+	require.Equal(t, 1, ace.Supplied)
+
+	_, err = jt.NewWith(1, 2, 3)
+	require.True(t, errors.Is(err, ErrArgCount))
+	require.True(t, errors.As(err, &ace))
+	require.Equal(t, 2, ace.Expected)
+	require.Equal(t, 3, ace.Supplied)
+	require.Equal(t, "too many args supplied (3) - expected maximum of 2", err.Error())
+
+	mt, err := NewMixedTemplate(`{"foo":?foo,"bar":?}`)
+	require.NoError(t, err)
+	_, err = mt.Data(nil)
+	require.True(t, errors.Is(err, ErrArgCount))
+}
+
+func TestArgMarshalError(t *testing.T) {
+	jt, err := NewTemplate("[\n?1,\n?2]")
+	require.NoError(t, err)
+
+	_, err = jt.String(1, func() {})
+	require.Error(t, err)
+	var ame *ArgMarshalError
+	require.True(t, errors.As(err, &ame))
+	require.Equal(t, "", ame.ArgName)
+	require.Equal(t, 1, ame.ArgIndex)
+	require.Equal(t, Position{Offset: 6, Line: 3, Column: 1}, ame.Position)
+	var ute *json.UnsupportedTypeError
+	require.True(t, errors.As(err, &ute))
+	require.Equal(t, "arg[1] at line 3, column 1: json: unsupported type: func()", err.Error())
+	require.Equal(t, "arg[1]: json: unsupported type: func()", (&ArgMarshalError{ArgIndex: 1, Err: ute}).Error())
+	require.Equal(t, "named arg 'foo': json: unsupported type: func()", (&ArgMarshalError{ArgName: "foo", ArgIndex: -1, Err: ute}).Error())
+
+	nt, err := NewNamedTemplate(`{"foo":?foo,"bar":?bar|upper}`)
+	require.NoError(t, err)
+	_, err = nt.String(map[string]interface{}{"foo": func() {}, "bar": "b"})
+	require.True(t, errors.As(err, &ame))
+	require.Equal(t, "foo", ame.ArgName)
+	require.Equal(t, -1, ame.ArgIndex)
+	require.Equal(t, 7, ame.Position.Offset)
+	require.True(t, errors.As(err, &ute))
+	require.Equal(t, "named arg 'foo' at line 1, column 8: json: unsupported type: func()", err.Error())
+
+	_, err = nt.String(map[string]interface{}{"foo": 1, "bar": 2})
+	require.True(t, errors.As(err, &ame))
+	require.Equal(t, "bar", ame.ArgName)
+	var fe *FilterError
+	require.True(t, errors.As(err, &fe))
+	require.Equal(t, "upper", fe.Filter)
+
+	_, err = nt.NewWith(map[string]interface{}{"foo": func() {}})
+	require.True(t, errors.As(err, &ame))
+
+	_, err = jt.NewWith(func() {})
+	require.True(t, errors.As(err, &ame))
+	require.Equal(t, 0, ame.ArgIndex)
+	require.Equal(t, 2, ame.Position.Offset)
+}
+
+func TestTemplateSyntaxError(t *testing.T) {
+	_, err := NewNamedTemplate("{\n\"foo\": ?}")
+	require.Error(t, err)
+	var tse *TemplateSyntaxError
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, Position{Offset: 9, Line: 2, Column: 8}, tse.Position)
+	require.Nil(t, tse.Unwrap())
+
+	_, err = NewTemplate("[\n?0]")
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, Position{Offset: 2, Line: 2, Column: 1}, tse.Position)
+
+	_, err = NewNamedTemplate(`{"foo":?foo|unknown}`)
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, 7, tse.Position.Offset)
+
+	_, err = NewTemplate("{\n  \"foo\": ?,\n  \"bar\" ?\n}", OptionChecked)
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, Position{Offset: 22, Line: 3, Column: 9}, tse.Position)
+	var se *json.SyntaxError
+	require.True(t, errors.As(err, &se))
+	require.Equal(t, "invalid JSON template at line 3, column 9: invalid character 'n' after object key", err.Error())
+
+	_, err = NewNamedTemplate(`{"foo":?foo`, OptionChecked)
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, Position{Offset: 7, Line: 1, Column: 8}, tse.Position)
+
+	_, err = NewMixedTemplate(`{"foo":?foo,"bar":?]`, OptionChecked)
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, Position{Offset: 19, Line: 1, Column: 20}, tse.Position)
+
+	// positions after '??' escapes are the template positions...
+	_, err = NewTemplate("[\"??\",?,\"???\" x]", OptionChecked)
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, Position{Offset: 14, Line: 1, Column: 15}, tse.Position)
+	_, err = NewNamedTemplate("{\"??\":\n\"??\" ?x}", OptionChecked)
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, Position{Offset: 12, Line: 2, Column: 6}, tse.Position)
+	_, err = NewMixedTemplate(`{"??":?,"??" ?x}`, OptionChecked)
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, Position{Offset: 13, Line: 1, Column: 14}, tse.Position)
+}
+
+func TestPositionOf(t *testing.T) {
+	data := []byte("ab\ncd\n\nef")
+	require.Equal(t, Position{Offset: 0, Line: 1, Column: 1}, positionOf(data, 0))
+	require.Equal(t, Position{Offset: 4, Line: 2, Column: 2}, positionOf(data, 4))
+	require.Equal(t, Position{Offset: 7, Line: 4, Column: 1}, positionOf(data, 7))
+	require.Equal(t, Position{Offset: 9, Line: 4, Column: 3}, positionOf(data, 100))
+}
+
+func TestNamedTemplateCollectErrors(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"foo":?foo,"bar":?bar,"baz":?baz,"qux":?qux,"again":?foo}`, OptionCollectErrors)
+	require.NoError(t, err)
+	require.True(t, (jt.(*jsonNamedTemplate)).collectErrors)
+
+	_, err = jt.String(map[string]interface{}{"baz": func() {}})
+	require.Error(t, err)
+	var aes *ArgErrors
+	require.True(t, errors.As(err, &aes))
+	require.Equal(t, 4, len(aes.Errors))
+	require.Equal(t, 4, len(aes.Unwrap()))
+	require.True(t, errors.Is(err, ErrMissingArg))
+	require.False(t, errors.Is(err, ErrArgCount))
+	var ame *ArgMarshalError
+	require.True(t, errors.As(err, &ame))
+	require.Equal(t, "baz", ame.ArgName)
+	var mae *MissingArgError
+	require.True(t, errors.As(err, &mae))
+	require.Equal(t, "foo", mae.ArgName)
+	require.Equal(t, "4 arg errors: expected named arg 'foo'; expected named arg 'bar'; named arg 'baz' at line 1, column 30: json: unsupported type: func(); expected named arg 'qux'", err.Error())
+
+	_, err = jt.Data(map[string]interface{}{"foo": 1, "bar": 2, "baz": 3})
+	require.True(t, errors.As(err, &aes))
+	require.Equal(t, 1, len(aes.Errors))
+
+	str, err := jt.String(map[string]interface{}{"foo": 1, "bar": 2, "baz": 3, "qux": 4})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":1,"bar":2,"baz":3,"qux":4,"again":1}`, str)
+
+	jt = jt.Options(OptionFailFast)
+	_, err = jt.String(map[string]interface{}{})
+	require.False(t, errors.As(err, &aes))
+	require.True(t, errors.As(err, &mae))
+
+	jt, err = jt.Options(OptionCollectErrors).NewWith(map[string]interface{}{"foo": 1})
+	require.NoError(t, err)
+	_, err = jt.String(map[string]interface{}{})
+	require.True(t, errors.As(err, &aes))
+	require.Equal(t, 3, len(aes.Errors))
+}
+
+func TestTemplateCollectErrors(t *testing.T) {
+	jt, err := NewTemplate(`[?1,?2,?1,?3]`, OptionCollectErrors)
+	require.NoError(t, err)
+
+	_, err = jt.String(func() {}, 1, func() {})
+	var aes *ArgErrors
+	require.True(t, errors.As(err, &aes))
+	require.Equal(t, 2, len(aes.Errors))
+	var ame *ArgMarshalError
+	require.True(t, errors.As(aes.Errors[1], &ame))
+	require.Equal(t, 2, ame.ArgIndex)
+	_, err = jt.Data(func() {}, 1, func() {})
+	require.True(t, errors.As(err, &aes))
+
+	_, err = jt.Data(1)
+	require.False(t, errors.As(err, &aes))
+	require.True(t, errors.Is(err, ErrArgCount))
+}
+
+func TestMixedTemplateCollectErrors(t *testing.T) {
+	jt, err := NewMixedTemplate(`[?foo,?,?bar]`, OptionCollectErrors)
+	require.NoError(t, err)
+
+	_, err = jt.String(map[string]interface{}{"bar": func() {}}, func() {})
+	var aes *ArgErrors
+	require.True(t, errors.As(err, &aes))
+	require.Equal(t, 3, len(aes.Errors))
+	_, err = jt.Data(map[string]interface{}{"bar": func() {}}, func() {})
+	require.True(t, errors.As(err, &aes))
+	require.Equal(t, 3, len(aes.Errors))
+}
+
+func TestArgErrorsIsAs(t *testing.T) {
+	aes := &ArgErrors{Errors: []error{errors.New("a")}}
+	require.False(t, errors.Is(aes, ErrMissingArg))
+	var mae *MissingArgError
+	require.False(t, errors.As(aes, &mae))
+	require.Equal(t, "1 arg errors: a", aes.Error())
+}
+
+func TestOptionCollectErrorsErrors(t *testing.T) {
+	err := OptionCollectErrors.Apply(nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,197 @@
+package jsont
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FilterFunc is the signature for value filters used on named arg markers - e.g.
+//   ?name|upper|trunc:40
+//
+// The value passed is the resolved named arg value (or the output of the previous filter) and the
+// args are any ':' separated args specified for the filter in the marker
+//
+// The value returned is passed to the next filter (if any) before being JSON marshalled
+type FilterFunc func(value interface{}, args ...string) (interface{}, error)
+
+type filterCall struct {
+	name string
+	args []string
+}
+
+// builtinFilters is the library of filters available to all templates (custom filters can be added with OptionFilters)
+var builtinFilters = map[string]FilterFunc{
+	"upper":   stringFilter(strings.ToUpper),
+	"lower":   stringFilter(strings.ToLower),
+	"trim":    stringFilter(strings.TrimSpace),
+	"trunc":   filterTrunc,
+	"string":  filterString,
+	"rfc3339": timeFilter(func(tm time.Time) interface{} { return tm.Format(time.RFC3339) }),
+	"date":    timeFilter(func(tm time.Time) interface{} { return tm.Format("2006-01-02") }),
+	"unix":    timeFilter(func(tm time.Time) interface{} { return tm.Unix() }),
+	"cents":   filterCents,
+}
+
+func stringFilter(fn func(string) string) FilterFunc {
+	return func(value interface{}, args ...string) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		} else if str, ok := filterStringValue(value); ok {
+			return fn(str), nil
+		}
+		return nil, fmt.Errorf("cannot filter value of type %T as string", value)
+	}
+}
+
+func filterStringValue(value interface{}) (string, bool) {
+	switch vt := value.(type) {
+	case string:
+		return vt, true
+	case []byte:
+		return string(vt), true
+	case fmt.Stringer:
+		return vt.String(), true
+	}
+	return "", false
+}
+
+func filterTrunc(value interface{}, args ...string) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("filter requires a single length arg (e.g. trunc:40)")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid length arg '%s'", args[0])
+	}
+	return stringFilter(func(s string) string {
+		if utf8.RuneCountInString(s) > n {
+			return string([]rune(s)[:n])
+		}
+		return s
+	})(value)
+}
+
+func filterString(value interface{}, args ...string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	} else if str, ok := filterStringValue(value); ok {
+		return str, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+func timeFilter(fn func(time.Time) interface{}) FilterFunc {
+	return func(value interface{}, args ...string) (interface{}, error) {
+		switch vt := value.(type) {
+		case nil:
+			return nil, nil
+		case time.Time:
+			return fn(vt), nil
+		case *time.Time:
+			if vt == nil {
+				return nil, nil
+			}
+			return fn(*vt), nil
+		}
+		return nil, fmt.Errorf("cannot filter value of type %T as time", value)
+	}
+}
+
+func filterCents(value interface{}, args ...string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(value)
+	// cents is the magnitude (so that the full range of int64 and uint64 values is handled)...
+	var cents uint64
+	sign := ""
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := rv.Int(); i < 0 {
+			sign = "-"
+			cents = uint64(-(i + 1)) + 1
+		} else {
+			cents = uint64(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		cents = rv.Uint()
+	default:
+		return nil, fmt.Errorf("cannot filter value of type %T as cents", value)
+	}
+	return json.Number(fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)), nil
+}
+
+func (t *jsonNamedTemplate) lookupFilter(name string) (FilterFunc, bool) {
+	if fn, ok := t.filters[name]; ok {
+		return fn, true
+	}
+	fn, ok := builtinFilters[name]
+	return fn, ok
+}
+
+func (t *jsonNamedTemplate) applyFilters(tkn jsonTemplateToken, value interface{}) (interface{}, error) {
+	for _, f := range tkn.filters {
+		fn, ok := t.lookupFilter(f.name)
+		if !ok {
+			return nil, newNamedArgMarshalError(tkn, &FilterError{Filter: f.name, Err: errors.New("unknown filter")})
+		}
+		var err error
+		if value, err = fn(value, f.args...); err != nil {
+			return nil, newNamedArgMarshalError(tkn, &FilterError{Filter: f.name, Err: err})
+		}
+	}
+	return value, nil
+}
+
+func (t *jsonNamedTemplate) checkFilters(tkns tokens) error {
+	for _, tkn := range tkns {
+		for _, f := range tkn.filters {
+			if _, ok := t.lookupFilter(f.name); !ok {
+				return &TemplateSyntaxError{
+					Position: tkn.pos,
+					message:  fmt.Sprintf("unknown filter '%s' on named arg '%s' at %s", f.name, tkn.argName, tkn.pos),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// scanForFilters scans for filters following a named arg marker - where i is the
+// position immediately after the arg name
+//
+// returns the filters found and the total length of the filters
+func scanForFilters(i int, data []byte) ([]filterCall, int) {
+	var result []filterCall
+	l := len(data)
+	j := i
+	for j+1 < l && data[j] == '|' && isArgNameChar(data[j+1]) {
+		start := j + 1
+		j = start
+		for j < l && isArgNameChar(data[j]) {
+			j++
+		}
+		f := filterCall{name: string(data[start:j])}
+		// a ':' is only an arg separator when followed by an arg (e.g. so that a filtered key marker keeps its ':')...
+		for j+1 < l && data[j] == ':' && isFilterArgChar(data[j+1]) {
+			argStart := j + 1
+			j = argStart
+			for j < l && isFilterArgChar(data[j]) {
+				j++
+			}
+			f.args = append(f.args, string(data[argStart:j]))
+		}
+		result = append(result, f)
+	}
+	return result, j - i
+}
+
+func isFilterArgChar(b byte) bool {
+	return isArgNameChar(b) || b == '.' || b == '+'
+}
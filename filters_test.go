@@ -0,0 +1,188 @@
+package jsont
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNamedTemplateFilters(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"code":?code|upper,"desc":?desc|trim|trunc:5,"at":?at|rfc3339,"amount":?amount|cents}`)
+	require.NoError(t, err)
+	expArgs := jt.ExpectedArgs()
+	require.Equal(t, 4, len(expArgs))
+	require.Contains(t, expArgs, "desc")
+
+	at := time.Date(2022, 7, 1, 12, 30, 0, 0, time.UTC)
+	str, err := jt.String(map[string]interface{}{"code": "gb", "desc": "  a long description ", "at": at, "amount": -1205})
+	require.NoError(t, err)
+	require.Equal(t, `{"code":"GB","desc":"a lon","at":"2022-07-01T12:30:00Z","amount":-12.05}`, str)
+
+	_, err = jt.String(map[string]interface{}{"code": 1, "desc": "", "at": at, "amount": 1})
+	require.Error(t, err)
+	require.Equal(t, "named arg 'code' at line 1, column 9: filter 'upper': cannot filter value of type int as string", err.Error())
+
+	jt = jt.DefaultArgValue("code", "us")
+	str, err = jt.String(map[string]interface{}{"desc": "", "at": &at, "amount": uint8(5)})
+	require.NoError(t, err)
+	require.Equal(t, `{"code":"US","desc":"","at":"2022-07-01T12:30:00Z","amount":0.05}`, str)
+
+	jt = jt.Options(OptionNonStrict)
+	str, err = jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"code":"US","desc":null,"at":null,"amount":null}`, str)
+}
+
+func TestNamedTemplateCustomFilters(t *testing.T) {
+	_, err := NewNamedTemplate(`{"foo":?foo|reverse}`)
+	require.Error(t, err)
+	require.Equal(t, "unknown filter 'reverse' on named arg 'foo' at line 1, column 8", err.Error())
+
+	reverse := func(value interface{}, args ...string) (interface{}, error) {
+		if str, ok := value.(string); ok {
+			r := []rune(str)
+			for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+				r[i], r[j] = r[j], r[i]
+			}
+			return string(r), nil
+		}
+		return nil, errors.New("not a string")
+	}
+	upper := func(value interface{}, args ...string) (interface{}, error) {
+		return strings.Join(append([]string{value.(string)}, args...), "-"), nil
+	}
+	jt, err := NewNamedTemplate(`{"foo":?foo|reverse|upper:a:b}`, OptionFilters(map[string]FilterFunc{"reverse": reverse}), OptionFilters(map[string]FilterFunc{"upper": upper}))
+	require.NoError(t, err)
+	require.Equal(t, 2, len((jt.(*jsonNamedTemplate)).filters))
+	str, err := jt.String(map[string]interface{}{"foo": "abc"})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"cba-a-b"}`, str)
+
+	_, err = jt.String(map[string]interface{}{"foo": 1})
+	require.Error(t, err)
+	require.Equal(t, "named arg 'foo' at line 1, column 8: filter 'reverse': not a string", err.Error())
+
+	_, err = NewTemplate(`{"foo":?}`, OptionFilters(map[string]FilterFunc{"reverse": reverse}))
+	require.Error(t, err)
+	require.Equal(t, "option OptionFilters cannot be applied to type '*jsont.jsonTemplate'", err.Error())
+}
+
+func TestNamedTemplateFilteredKey(t *testing.T) {
+	jt, err := NewNamedTemplate(`{?k|lower:?v,?k2|trunc:2:?v}`)
+	require.NoError(t, err)
+	str, err := jt.String(map[string]interface{}{"k": "KEY", "k2": "other", "v": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"key":1,"ot":1}`, str)
+}
+
+func TestNamedTemplateFiltersNewWith(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"foo":?foo|upper,"bar":?bar|lower}`)
+	require.NoError(t, err)
+	jt, err = jt.NewWith(map[string]interface{}{"foo": "aaa"})
+	require.NoError(t, err)
+	str, err := jt.String(map[string]interface{}{"bar": "BBB"})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"AAA","bar":"bbb"}`, str)
+
+	_, err = jt.NewWith(map[string]interface{}{"bar": true})
+	require.Error(t, err)
+}
+
+func TestMixedTemplateFilters(t *testing.T) {
+	jt, err := NewMixedTemplate(`{"foo":?foo|upper,"bar":?}`)
+	require.NoError(t, err)
+	str, err := jt.String(map[string]interface{}{"foo": "aaa"}, "bbb")
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"AAA","bar":"bbb"}`, str)
+
+	_, err = NewMixedTemplate(`{"foo":?foo|unknown,"bar":?}`)
+	require.Error(t, err)
+}
+
+func TestScanForFilters(t *testing.T) {
+	testCases := []struct {
+		data        string
+		expectLen   int
+		expectCalls []filterCall
+	}{
+		{``, 0, nil},
+		{`,`, 0, nil},
+		{`|`, 0, nil},
+		{`|}`, 0, nil},
+		{`|upper}`, 6, []filterCall{{name: "upper"}}},
+		{`|upper|trunc:40,`, 15, []filterCall{{name: "upper"}, {name: "trunc", args: []string{"40"}}}},
+		{`|a:1:2.5|b:`, 10, []filterCall{{name: "a", args: []string{"1", "2.5"}}, {name: "b"}}},
+		{`|lower:?v}`, 6, []filterCall{{name: "lower"}}},
+		{`|trunc:3:?v}`, 8, []filterCall{{name: "trunc", args: []string{"3"}}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.data, func(t *testing.T) {
+			calls, l := scanForFilters(0, []byte(tc.data))
+			require.Equal(t, tc.expectLen, l)
+			require.Equal(t, tc.expectCalls, calls)
+		})
+	}
+}
+
+type testStringer struct{}
+
+func (s testStringer) String() string {
+	return "Stringer"
+}
+
+func TestBuiltinFilters(t *testing.T) {
+	at := time.Date(2022, 7, 1, 12, 30, 0, 0, time.UTC)
+	var nilTime *time.Time
+	testCases := []struct {
+		filter    string
+		value     interface{}
+		args      []string
+		expect    interface{}
+		expectErr bool
+	}{
+		{filter: "upper", value: nil, expect: nil},
+		{filter: "upper", value: []byte("abc"), expect: "ABC"},
+		{filter: "upper", value: testStringer{}, expect: "STRINGER"},
+		{filter: "lower", value: "ABC", expect: "abc"},
+		{filter: "trim", value: " abc ", expect: "abc"},
+		{filter: "trunc", value: "héllo", args: []string{"2"}, expect: "hé"},
+		{filter: "trunc", value: "abc", args: []string{"5"}, expect: "abc"},
+		{filter: "trunc", value: "abc", expectErr: true},
+		{filter: "trunc", value: "abc", args: []string{"x"}, expectErr: true},
+		{filter: "trunc", value: "abc", args: []string{"-1"}, expectErr: true},
+		{filter: "string", value: nil, expect: nil},
+		{filter: "string", value: 1.5, expect: "1.5"},
+		{filter: "string", value: testStringer{}, expect: "Stringer"},
+		{filter: "date", value: at, expect: "2022-07-01"},
+		{filter: "unix", value: at, expect: at.Unix()},
+		{filter: "unix", value: nilTime, expect: nil},
+		{filter: "unix", value: nil, expect: nil},
+		{filter: "unix", value: "x", expectErr: true},
+		{filter: "cents", value: nil, expect: nil},
+		{filter: "cents", value: 1, expect: "0.01"},
+		{filter: "cents", value: 1.5, expectErr: true},
+		{filter: "cents", value: -105, expect: "-1.05"},
+		{filter: "cents", value: uint8(7), expect: "0.07"},
+		{filter: "cents", value: uint64(math.MaxUint64), expect: "184467440737095516.15"},
+		{filter: "cents", value: int64(math.MaxInt64), expect: "92233720368547758.07"},
+		{filter: "cents", value: int64(math.MinInt64), expect: "-92233720368547758.08"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.filter, func(t *testing.T) {
+			v, err := builtinFilters[tc.filter](tc.value, tc.args...)
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				if tc.filter == "cents" && v != nil {
+					v = string(v.(json.Number))
+				}
+				require.Equal(t, tc.expect, v)
+			}
+		})
+	}
+}
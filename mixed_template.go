@@ -0,0 +1,339 @@
+package jsont
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// MixedTemplate is a JSON template with both positional and named args
+type MixedTemplate interface {
+	// String produces a JSON string from the template using the specified named and positional args
+	//
+	// Named args are resolved in the same way as for NamedTemplate (i.e. defaults and strictness apply)
+	//
+	// The number of positional args must match positional args specified in the original template
+	// (unless the template has not been made Strict)
+	//
+	// Each arg must be able to JSON Marshall
+	String(named map[string]interface{}, positional ...interface{}) (string, error)
+	// Data produces a JSON []byte data from the template using the specified named and positional args
+	//
+	// Named args are resolved in the same way as for NamedTemplate (i.e. defaults and strictness apply)
+	//
+	// The number of positional args must match positional args specified in the original template
+	// (unless the template has not been made Strict)
+	//
+	// Each arg must be able to JSON Marshall
+	Data(named map[string]interface{}, positional ...interface{}) ([]byte, error)
+	// RenderContext produces a JSON []byte data from the template using the specified named and positional args -
+	// in the same way as Data
+	//
+	// Any arg (or default value) can be a lazy value - a func(ctx context.Context) (interface{}, error) - which
+	// is called (with the context) to obtain the actual arg value
+	//
+	// Rendering stops (and returns the context error) if the context is cancelled
+	RenderContext(ctx context.Context, named map[string]interface{}, positional ...interface{}) ([]byte, error)
+	// ExpectedArgs returns a map of expected arg names (the boolean value for each map entry
+	// indicates whether the template has a default value for that named arg) and the expected
+	// number of positional args
+	ExpectedArgs() (map[string]bool, int)
+	// Args returns information about each named and positional arg marker in the template (in template order) -
+	// including the position of the marker and the JSON Pointer of where the arg value lands in the output
+	Args() []ArgInfo
+	// Schema generates a JSON Schema (draft 2020-12) describing the output of the template
+	//
+	// Fixed parts of the template are described as const values and arg markers are described as
+	// untyped (i.e. '{}') schemas - except where the type is known (e.g. from a built-in filter) and with
+	// any default value
+	//
+	// An error is returned if the structure of the template could not be determined
+	Schema() (map[string]interface{}, error)
+	// DefaultArgValue returns a copy of the template with a default value for a specific named arg - the template
+	// itself is unchanged
+	//
+	// The default value can be a func() interface{} or func(name string) (interface{}, error) - which is called
+	// each time the default is used (e.g. for defaults such as a current timestamp)
+	DefaultArgValue(argName string, value interface{}) MixedTemplate
+	// DefaultArgValues returns a copy of the template with default values for the specified named args - the
+	// template itself is unchanged
+	DefaultArgValues(defaults map[string]interface{}) MixedTemplate
+	// Bind binds the template with the specified named and positional args - the bound template can then be used as an
+	// arg value for another template (and is rendered directly into the output of the other template)
+	Bind(named map[string]interface{}, positional ...interface{}) *BoundTemplate
+	// Options returns a copy of the template with the specified options applied - the template itself is unchanged (templates
+	// are never changed in place, so can be safely shared and rendered concurrently)
+	//
+	// Note: unlike using options with NewMixedTemplate and MustCompileMixedTemplate, this method
+	// does not panic or error if any of the options are not applicable to this type
+	Options(options ...Option) MixedTemplate
+	// Clone creates a copy of the template - the copy shares the parsed template and copies only the
+	// configuration (e.g. strictness and default arg values)
+	Clone() MixedTemplate
+	// With creates a copy of the template with the specified options applied - the copy shares the parsed template
+	// (so the template string is not re-parsed)
+	//
+	// Unlike Options, an error is returned if any of the options cannot be applied
+	With(options ...Option) (MixedTemplate, error)
+}
+
+type jsonMixedTemplate struct {
+	named         *jsonNamedTemplate
+	argsCount     int
+	tokens        tokens
+	argInfos      []ArgInfo
+	fixedLens     int
+	strict        bool
+	checkReqd     bool
+	collectErrors bool
+	schema        *jsonSchema
+	validation    jsonValidation
+}
+
+// NewMixedTemplate creates a new JSON template from a template string
+//
+// The template string can be any JSON with positional arg positions specified by '?' and
+// named arg positions specified by '?name'
+//
+// To escape a '?' in the template, use '??'
+//
+// Positional arg positions can also be indexed - e.g. '?1', '?2' - so that the same positional arg
+// can be used more than once in the template (see NewTemplate)
+//
+// Example:
+//   jt, _ := NewMixedTemplate(`{"foo":?foo,"bar":?,"baz":"??","qux":?}`)
+//   println(jt.String(map[string]interface{}{"foo":"aaa"}, true, 1.2))
+// would produce:
+//   {"foo":"aaa","bar":true,"baz":"?","qux":1.2}
+func NewMixedTemplate(template string, options ...Option) (MixedTemplate, error) {
+	result := &jsonMixedTemplate{
+		named: &jsonNamedTemplate{
+			argNames:         map[string]bool{},
+			tokens:           tokens{},
+			defaultArgValues: map[string]interface{}{},
+			strict:           true,
+		},
+		tokens: make(tokens, 0),
+		strict: true,
+	}
+	if err := result.parse(template); err != nil {
+		return nil, err
+	}
+	if err := result.applyOptions(options, false); err != nil {
+		return nil, err
+	}
+	if err := result.named.checkFilters(result.tokens); err != nil {
+		return nil, err
+	}
+	if err := result.check(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MustCompileMixedTemplate is the same as NewMixedTemplate, except it panics if there is an error
+func MustCompileMixedTemplate(template string, options ...Option) MixedTemplate {
+	if jt, err := NewMixedTemplate(template, options...); err == nil {
+		return jt
+	} else {
+		panic(any(err))
+	}
+}
+
+// Options returns a copy of the template with the options applied (see MixedTemplate.Options)
+func (t *jsonMixedTemplate) Options(options ...Option) MixedTemplate {
+	result := t.clone()
+	_ = result.applyOptions(options, true)
+	return result
+}
+
+// Clone creates a copy of the template (see MixedTemplate.Clone)
+func (t *jsonMixedTemplate) Clone() MixedTemplate {
+	return t.clone()
+}
+
+// With creates a copy of the template with the options applied (see MixedTemplate.With)
+func (t *jsonMixedTemplate) With(options ...Option) (MixedTemplate, error) {
+	result := t.clone()
+	if err := result.applyOptions(options, false); err != nil {
+		return nil, err
+	}
+	if err := result.check(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// clone copies the template and its underlying named template - the tokens are
+// immutable and are shared
+func (t *jsonMixedTemplate) clone() *jsonMixedTemplate {
+	result := *t
+	result.named = t.named.clone()
+	return &result
+}
+
+func (t *jsonMixedTemplate) applyOptions(options []Option, ignoreErrs bool) error {
+	for _, o := range options {
+		if o != nil {
+			if err := o.Apply(t); err != nil && !ignoreErrs {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// String produces a JSON string from the template using the specified named and positional args
+//
+// Named args are resolved in the same way as for NamedTemplate (i.e. defaults and strictness apply)
+//
+// The number of positional args must match positional args specified in the original template
+// (unless the template has not been made Strict)
+//
+// Each arg must be able to JSON Marshall
+func (t *jsonMixedTemplate) String(named map[string]interface{}, positional ...interface{}) (string, error) {
+	validator := newOutputValidator(t.validation)
+	var builder strings.Builder
+	if err := t.renderTo(context.Background(), &builder, named, positional, validator); err != nil {
+		return "null", err
+	}
+	result := builder.String()
+	if validator != nil || t.schema != nil {
+		// only copied to data when the output is validated...
+		data := []byte(result)
+		if err := validator.validate(data); err != nil {
+			return "null", err
+		} else if err = t.schema.validate(data); err != nil {
+			return "null", err
+		}
+	}
+	return result, nil
+}
+
+// Data produces a JSON []byte data from the template using the specified named and positional args
+//
+// Named args are resolved in the same way as for NamedTemplate (i.e. defaults and strictness apply)
+//
+// The number of positional args must match positional args specified in the original template
+// (unless the template has not been made Strict)
+//
+// Each arg must be able to JSON Marshall
+func (t *jsonMixedTemplate) Data(named map[string]interface{}, positional ...interface{}) ([]byte, error) {
+	return t.RenderContext(context.Background(), named, positional...)
+}
+
+// RenderContext produces a JSON []byte data from the template using the context (see MixedTemplate.RenderContext)
+func (t *jsonMixedTemplate) RenderContext(ctx context.Context, named map[string]interface{}, positional ...interface{}) ([]byte, error) {
+	data, err := t.data(ctx, named, positional, newOutputValidator(t.validation))
+	if err == nil {
+		err = t.schema.validate(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// data produces the JSON data from the template (without validating against any schema) - the output is
+// validated only if a validator is supplied
+func (t *jsonMixedTemplate) data(ctx context.Context, named map[string]interface{}, positional []interface{}, validator *outputValidator) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := t.renderTo(ctx, &buffer, named, positional, validator); err != nil {
+		return nil, err
+	} else if err = validator.validate(buffer.Bytes()); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// renderTo renders the template (using the specified named and positional args) into the writer
+func (t *jsonMixedTemplate) renderTo(ctx context.Context, w renderWriter, named map[string]interface{}, positional []interface{}, validator *outputValidator) error {
+	if err := checkArgsCount(t.strict, t.argsCount, positional); err != nil {
+		return err
+	}
+	collector := newArgErrorsCollector(t.collectErrors)
+	if err := t.named.checkUnknownArgs(MapArgs(named)); err != nil {
+		if collector == nil {
+			return err
+		}
+		collector.add(unknownArgsKey, err)
+	}
+	argsData, writers, argsLen, err := getArgsData(ctx, positional, t.argsCount, t.tokens, t.validation, collector)
+	if err != nil {
+		return err
+	}
+	w.Grow(t.fixedLens + argsLen)
+	return renderTokens(ctx, w, t.tokens, argsData, writers, t.named, MapArgs(named), validator, collector)
+}
+
+// ExpectedArgs returns a map of expected arg names (the boolean value for each map entry
+// indicates whether the template has a default value for that named arg) and the expected
+// number of positional args
+func (t *jsonMixedTemplate) ExpectedArgs() (map[string]bool, int) {
+	return t.named.ExpectedArgs(), t.argsCount
+}
+
+// Args returns information about each named and positional arg marker in the template (see MixedTemplate.Args)
+func (t *jsonMixedTemplate) Args() []ArgInfo {
+	return copyArgInfos(t.argInfos)
+}
+
+// Schema generates a JSON Schema describing the output of the template (see MixedTemplate.Schema)
+func (t *jsonMixedTemplate) Schema() (map[string]interface{}, error) {
+	return t.tokens.schema(func(tkn jsonTemplateToken) map[string]interface{} {
+		if tkn.argName != "" {
+			return t.named.namedArgSchema(tkn)
+		}
+		return positionalArgSchema(tkn)
+	})
+}
+
+// DefaultArgValue returns a copy of the template with the default (see MixedTemplate.DefaultArgValue)
+func (t *jsonMixedTemplate) DefaultArgValue(argName string, value interface{}) MixedTemplate {
+	result := t.clone()
+	result.named.setDefaultArgValue(argName, value)
+	return result
+}
+
+// DefaultArgValues returns a copy of the template with the defaults (see MixedTemplate.DefaultArgValues)
+func (t *jsonMixedTemplate) DefaultArgValues(defaults map[string]interface{}) MixedTemplate {
+	result := t.clone()
+	for k, v := range defaults {
+		result.named.setDefaultArgValue(k, v)
+	}
+	return result
+}
+
+func (t *jsonMixedTemplate) setStrict(strict bool) {
+	t.strict = strict
+	t.named.strict = strict
+}
+
+func (t *jsonMixedTemplate) parse(template string) error {
+	p, err := parseTemplate(template, true, true)
+	if err != nil {
+		return err
+	}
+	t.tokens = p.tokens.joinContiguousFixed()
+	t.argInfos = t.tokens.argInfos()
+	t.fixedLens = p.fixedLens
+	t.argsCount = p.argsCount
+	t.named.argNames = p.argNames
+	return nil
+}
+
+func (t *jsonMixedTemplate) check() (err error) {
+	if t.checkReqd {
+		tArgs := map[string]interface{}{}
+		for k := range t.named.argNames {
+			tArgs[k] = nil
+		}
+		tData, _ := t.data(context.Background(), tArgs, make([]interface{}, t.argsCount), nil)
+		var v interface{}
+		if err = json.Unmarshal(tData, &v); err != nil {
+			err = t.tokens.checkError(err)
+		}
+	}
+	return
+}
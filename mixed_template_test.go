@@ -0,0 +1,178 @@
+package jsont
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMixedTemplate(t *testing.T) {
+	jt, err := NewMixedTemplate(`{"foo":?foo,"bar":?,"baz":"??","qux":?}`)
+	require.NoError(t, err)
+	require.NotNil(t, jt)
+	require.Equal(t, 2, (jt.(*jsonMixedTemplate)).argsCount)
+	named, positional := jt.ExpectedArgs()
+	require.Equal(t, 1, len(named))
+	require.False(t, named["foo"])
+	require.Equal(t, 2, positional)
+
+	str, err := jt.String(map[string]interface{}{"foo": "aaa"}, true, 1.2)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"aaa","bar":true,"baz":"?","qux":1.2}`, str)
+	data, err := jt.Data(map[string]interface{}{"foo": "aaa"}, true, 1.2)
+	require.NoError(t, err)
+	require.Equal(t, str, string(data[:]))
+
+	_, err = jt.String(map[string]interface{}{"foo": "aaa"}, true)
+	require.Error(t, err)
+	require.Equal(t, "expected 2 args but supplied 1 args", err.Error())
+	_, err = jt.Data(map[string]interface{}{"foo": "aaa"}, true)
+	require.Error(t, err)
+
+	_, err = jt.String(map[string]interface{}{}, true, 1.2)
+	require.Error(t, err)
+	require.Equal(t, "expected named arg 'foo'", err.Error())
+	_, err = jt.Data(map[string]interface{}{}, true, 1.2)
+	require.Error(t, err)
+
+	jt = jt.DefaultArgValue("foo", "xxx")
+	named, _ = jt.ExpectedArgs()
+	require.True(t, named["foo"])
+	str, err = jt.String(nil, true, 1.2)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"xxx","bar":true,"baz":"?","qux":1.2}`, str)
+	jt = jt.DefaultArgValues(map[string]interface{}{"foo": "yyy"})
+	str, err = jt.String(nil, true, 1.2)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"yyy","bar":true,"baz":"?","qux":1.2}`, str)
+
+	_, err = jt.String(nil, func() {}, 1.2)
+	require.Error(t, err)
+	_, err = jt.Data(nil, func() {}, 1.2)
+	require.Error(t, err)
+}
+
+func TestMixedTemplateNonStrict(t *testing.T) {
+	jt, err := NewMixedTemplate(`{"foo":?foo,"bar":?,"qux":?}`, OptionNonStrict)
+	require.NoError(t, err)
+	require.False(t, (jt.(*jsonMixedTemplate)).strict)
+	require.False(t, (jt.(*jsonMixedTemplate)).named.strict)
+
+	str, err := jt.String(nil, true)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":null,"bar":true,"qux":null}`, str)
+
+	// extra positional args are ignored...
+	str, err = jt.String(nil, 1, 2, 3)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":null,"bar":1,"qux":2}`, str)
+
+	jt = jt.Options(OptionStrict)
+	_, err = jt.String(nil, true)
+	require.Error(t, err)
+}
+
+func TestMixedTemplateOptions(t *testing.T) {
+	_, err := NewMixedTemplate(`{?foo,?}`, OptionChecked)
+	require.Error(t, err)
+	jt, err := NewMixedTemplate(`{"foo":?foo,"bar":?}`, OptionChecked)
+	require.NoError(t, err)
+	require.True(t, (jt.(*jsonMixedTemplate)).checkReqd)
+
+	jt, err = NewMixedTemplate(`{"foo":?foo,"bar":?}`, OptionDefaultArgValue("foo", 1), OptionDefaultArgValues(map[string]interface{}{"bar": 2}))
+	require.NoError(t, err)
+	require.Equal(t, 2, len((jt.(*jsonMixedTemplate)).named.defaultArgValues))
+
+	_, err = NewMixedTemplate(`{"foo":?foo}`, &erroringOption{})
+	require.Error(t, err)
+}
+
+func TestMustCompileMixedTemplate(t *testing.T) {
+	jt := MustCompileMixedTemplate(`{"foo":?foo,"bar":?}`)
+	require.NotNil(t, jt)
+	require.Equal(t, 5, len((jt.(*jsonMixedTemplate)).tokens))
+
+	require.Panics(t, func() {
+		MustCompileMixedTemplate(`{?}`, OptionChecked)
+	})
+}
+
+func TestMixedTemplateIndexedArgs(t *testing.T) {
+	jt, err := NewMixedTemplate(`{"id":?1,"parent":{"id":?1},"name":?name,"other":?2}`)
+	require.NoError(t, err)
+	named, positional := jt.ExpectedArgs()
+	require.Equal(t, 1, len(named))
+	require.Equal(t, 2, positional)
+	str, err := jt.String(map[string]interface{}{"name": "aaa"}, "a1", "b2")
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"a1","parent":{"id":"a1"},"name":"aaa","other":"b2"}`, str)
+
+	_, err = NewMixedTemplate(`{"id":?0}`)
+	require.Error(t, err)
+	require.Equal(t, "invalid arg index '0' at position 6", err.Error())
+	_, err = NewMixedTemplate(`[?1,?name,?]`)
+	require.Error(t, err)
+	require.Equal(t, "cannot mix '?' and indexed '?n' positional args at position 10", err.Error())
+	_, err = NewMixedTemplate(`[?name,?2]`)
+	require.Error(t, err)
+	require.Equal(t, "arg index '2' at position 7 skips unused arg index '1'", err.Error())
+}
+
+func TestMixedTemplateCopyOnWrite(t *testing.T) {
+	jt := MustCompileMixedTemplate(`{"a":?a,"b":?}`)
+	jt2 := jt.DefaultArgValue("a", "x")
+	jt3 := jt2.DefaultArgValues(map[string]interface{}{"a": "y"}).Options(OptionNonStrict)
+	require.NotSame(t, jt, jt2)
+	require.NotSame(t, jt2, jt3)
+	require.NotSame(t, (jt2.(*jsonMixedTemplate)).named, (jt3.(*jsonMixedTemplate)).named)
+
+	_, err := jt.String(nil, 1)
+	require.Error(t, err)
+	str, err := jt2.String(nil, 1)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":"x","b":1}`, str)
+	str, err = jt3.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":"y","b":null}`, str)
+	_, err = jt2.String(nil)
+	require.Error(t, err)
+}
+
+func TestMixedTemplateCloneAndWith(t *testing.T) {
+	jt := MustCompileMixedTemplate(`{"a":?a,"b":?}`)
+	clone := jt.Clone().DefaultArgValue("a", 1)
+	require.NotSame(t, (jt.(*jsonMixedTemplate)).named, (clone.(*jsonMixedTemplate)).named)
+	_, err := jt.String(nil, 2)
+	require.Error(t, err)
+	str, err := clone.String(nil, 2)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":2}`, str)
+
+	jt2, err := jt.With(OptionNonStrict)
+	require.NoError(t, err)
+	require.NotSame(t, (jt.(*jsonMixedTemplate)).named, (jt2.(*jsonMixedTemplate)).named)
+	str, err = jt2.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":null,"b":null}`, str)
+
+	_, err = jt.With(OptionChecked, OptionSchema([]byte(`[]`)))
+	require.Error(t, err)
+}
+
+func TestMixedTemplateRenderContext(t *testing.T) {
+	jt := MustCompileMixedTemplate(`{"foo":?foo,"bar":?}`)
+	lazy := func(ctx context.Context) (interface{}, error) {
+		return ctx.Value(testCtxKey{}), nil
+	}
+	ctx := context.WithValue(context.Background(), testCtxKey{}, "value")
+	data, err := jt.RenderContext(ctx, map[string]interface{}{"foo": lazy}, lazy)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"value","bar":"value"}`, string(data))
+
+	cctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = jt.RenderContext(cctx, map[string]interface{}{"foo": 1}, 2)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.Canceled))
+}
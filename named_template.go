@@ -2,8 +2,8 @@ package jsont
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"strings"
 )
 
@@ -33,30 +33,92 @@ type NamedTemplate interface {
 	//
 	// Each arg must be able to JSON Marshall
 	Data(args map[string]interface{}) ([]byte, error)
+	// Render produces a JSON []byte data from the template using the specified arg resolver to resolve named args
+	//
+	// Missing named args (i.e. those not resolved by the resolver) are treated in the same way as for Data
+	Render(resolver ArgResolver) ([]byte, error)
+	// RenderString produces a JSON string from the template using the specified arg resolver to resolve named args
+	//
+	// Missing named args (i.e. those not resolved by the resolver) are treated in the same way as for String
+	RenderString(resolver ArgResolver) (string, error)
+	// RenderContext produces a JSON []byte data from the template using the specified arg resolver to resolve
+	// named args - in the same way as Render
+	//
+	// Any arg (or default value) can be a lazy value - a func(ctx context.Context) (interface{}, error) - which
+	// is called (with the context) to obtain the actual arg value
+	//
+	// Rendering stops (and returns the context error) if the context is cancelled
+	RenderContext(ctx context.Context, resolver ArgResolver) ([]byte, error)
 	// ExpectedArgs returns a map of expected arg names - the boolean
 	// value for each map entry indicates whether the template has a
 	// default value for that named arg
 	ExpectedArgs() map[string]bool
-	// DefaultArgValue provides a default value for a specific named arg
+	// Args returns information about each arg marker in the template (in template order) - including
+	// the position of the marker and the JSON Pointer of where the arg value lands in the output
+	Args() []ArgInfo
+	// Schema generates a JSON Schema (draft 2020-12) describing the output of the template
+	//
+	// Fixed parts of the template are described as const values and arg markers are described as
+	// untyped (i.e. '{}') schemas - except where the type is known (e.g. from a built-in filter) and with
+	// any default value
+	//
+	// An error is returned if the structure of the template could not be determined
+	Schema() (map[string]interface{}, error)
+	// DefaultArgValue returns a copy of the template with a default value for a specific named arg - the template
+	// itself is unchanged
+	//
+	// The default value can be a func() interface{} or func(name string) (interface{}, error) - which is called
+	// each time the default is used (e.g. for defaults such as a current timestamp)
 	DefaultArgValue(argName string, value interface{}) NamedTemplate
-	// DefaultArgValues provides default values for the specified named args
+	// DefaultArgValues returns a copy of the template with default values for the specified named args - the
+	// template itself is unchanged
 	DefaultArgValues(defaults map[string]interface{}) NamedTemplate
 	// NewWith creates a new template with the args supplied being resolved in the new template
 	//
+	// Lazy arg values (see RenderContext) are resolved using context.Background()
+	//
 	// Note: when resolving args into the new template, defaults are NOT used (but are copied over to the new)
 	NewWith(args map[string]interface{}) (NamedTemplate, error)
+	// NewWithDefaults creates a new template with the args supplied being resolved in the new template - and, for
+	// args not supplied, default values are also resolved (baked) into the new template
+	//
+	// Note: defaults that are not pre-encoded (e.g. lazy defaults - see LazyDefault) and env args are not resolved
+	// into the new template
+	NewWithDefaults(args map[string]interface{}) (NamedTemplate, error)
+	// Bind binds the template with the specified args - the bound template can then be used as an arg value for
+	// another template (and is rendered directly into the output of the other template)
+	Bind(args map[string]interface{}) *BoundTemplate
+	// Options returns a copy of the template with the specified options applied - the template itself is unchanged (templates
+	// are never changed in place, so can be safely shared and rendered concurrently)
+	//
+	// Note: unlike using options with NewNamedTemplate and MustCompileNamedTemplate, this method
+	// does not panic or error if any of the options are not applicable to this type
 	Options(options ...Option) NamedTemplate
+	// Clone creates a copy of the template - the copy shares the parsed template and copies only the
+	// configuration (e.g. strictness and default arg values)
+	Clone() NamedTemplate
+	// With creates a copy of the template with the specified options applied - the copy shares the parsed template
+	// (so the template string is not re-parsed)
+	//
+	// Unlike Options, an error is returned if any of the options cannot be applied
+	With(options ...Option) (NamedTemplate, error)
 }
 
 type jsonNamedTemplate struct {
 	argNames         map[string]bool
 	tokens           tokens
+	argInfos         []ArgInfo
 	fixedLens        int
 	strict           bool
 	checkReqd        bool
+	collectErrors    bool
+	rejectUnknown    bool
 	defaultArgValues map[string]interface{}
-	// used only during parsing...
-	lastTokenStart int
+	envArgs          bool
+	envArgTypes      map[string]EnvArgType
+	filters          map[string]FilterFunc
+	schema           *jsonSchema
+	validation       jsonValidation
 }
 
 // NewNamedTemplate creates a new JSON template from a template string
@@ -65,6 +127,18 @@ type jsonNamedTemplate struct {
 //
 // To escape a '?' in the template, use '??'
 //
+// Arg names may be dotted paths (e.g. '?user.address.city') - which, when there is no arg with that exact
+// name, are resolved by walking into nested maps, structs (using json tag names) and slices (using numeric segments)
+//
+// Named arg markers can specify fallback named args using '?:' (e.g. '?preferredName?:fallbackName') - the
+// first present, non-nil arg is used (with any default values used as the final fallback)
+//
+// Named arg markers can specify filters to be applied to the value (e.g. '?name|upper|trunc:40') - see
+// OptionFilters for the built-in filters and adding custom filters
+//
+// Named args prefixed with 'env.' (e.g. '?env.DATABASE_URL') can be resolved from environment
+// variables - see OptionEnvArgs and OptionEnvArgTypes
+//
 // Example:
 //   jt, _ := NewNamedTemplate(`{"foo":?foo,"bar":?bar,"baz":"??","qux":?qux}`)
 //   println(jt.String(map[string]interface{}{"foo":"aaa", "bar":true, "qux":1.2}))
@@ -83,6 +157,9 @@ func NewNamedTemplate(template string, options ...Option) (NamedTemplate, error)
 	if err := result.applyOptions(options, false); err != nil {
 		return nil, err
 	}
+	if err := result.checkFilters(result.tokens); err != nil {
+		return nil, err
+	}
 	if err := result.check(); err != nil {
 		return nil, err
 	}
@@ -98,13 +175,39 @@ func MustCompileNamedTemplate(template string, options ...Option) NamedTemplate
 	}
 }
 
-// Options applies the specified options to the template
-//
-// Note: unlike using options with NewNamedTemplate and MustCompileNamedTemplate, this method
-// does not panic or error if any of the options are not applicable to this type
+// Options returns a copy of the template with the options applied (see NamedTemplate.Options)
 func (t *jsonNamedTemplate) Options(options ...Option) NamedTemplate {
-	_ = t.applyOptions(options, true)
-	return t
+	result := t.clone()
+	_ = result.applyOptions(options, true)
+	return result
+}
+
+// Clone creates a copy of the template (see NamedTemplate.Clone)
+func (t *jsonNamedTemplate) Clone() NamedTemplate {
+	return t.clone()
+}
+
+// With creates a copy of the template with the options applied (see NamedTemplate.With)
+func (t *jsonNamedTemplate) With(options ...Option) (NamedTemplate, error) {
+	result := t.clone()
+	if err := result.applyOptions(options, false); err != nil {
+		return nil, err
+	}
+	if err := result.check(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// clone copies the template - the tokens (and arg names) are immutable and are shared,
+// the default arg values are copied (filters and env arg types are replaced, rather than changed, when options are applied)
+func (t *jsonNamedTemplate) clone() *jsonNamedTemplate {
+	result := *t
+	result.defaultArgValues = make(map[string]interface{}, len(t.defaultArgValues))
+	for k, v := range t.defaultArgValues {
+		result.defaultArgValues[k] = v
+	}
+	return &result
 }
 
 func (t *jsonNamedTemplate) applyOptions(options []Option, ignoreErrs bool) error {
@@ -130,18 +233,7 @@ func (t *jsonNamedTemplate) applyOptions(options []Option, ignoreErrs bool) erro
 //
 // Each arg must be able to JSON Marshall
 func (t *jsonNamedTemplate) String(args map[string]interface{}) (string, error) {
-	var builder strings.Builder
-	builder.Grow(t.fixedLens)
-	for _, tkn := range t.tokens {
-		if tkn.fixed {
-			builder.Write(tkn.fixedValue)
-		} else if ad, err := t.getNamedArgValue(tkn.argName, args); err == nil {
-			builder.Write(ad)
-		} else {
-			return "", err
-		}
-	}
-	return builder.String(), nil
+	return t.RenderString(MapArgs(args))
 }
 
 // Data produces a JSON []byte data from the template using the specified args
@@ -156,20 +248,78 @@ func (t *jsonNamedTemplate) String(args map[string]interface{}) (string, error)
 //
 // Each arg must be able to JSON Marshall
 func (t *jsonNamedTemplate) Data(args map[string]interface{}) ([]byte, error) {
+	return t.Render(MapArgs(args))
+}
+
+// Render produces a JSON []byte data from the template using the specified arg resolver to resolve named args
+//
+// Missing named args (i.e. those not resolved by the resolver) are treated in the same way as for Data
+func (t *jsonNamedTemplate) Render(resolver ArgResolver) ([]byte, error) {
+	return t.RenderContext(context.Background(), resolver)
+}
+
+// RenderContext produces a JSON []byte data from the template using the context (see NamedTemplate.RenderContext)
+func (t *jsonNamedTemplate) RenderContext(ctx context.Context, resolver ArgResolver) ([]byte, error) {
+	data, err := t.render(ctx, resolver, newOutputValidator(t.validation))
+	if err == nil {
+		err = t.schema.validate(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// render produces the JSON data from the template (without validating against any schema) - the output is
+// validated only if a validator is supplied
+func (t *jsonNamedTemplate) render(ctx context.Context, resolver ArgResolver, validator *outputValidator) ([]byte, error) {
 	var buffer bytes.Buffer
-	buffer.Grow(t.fixedLens)
-	for _, tkn := range t.tokens {
-		if tkn.fixed {
-			buffer.Write(tkn.fixedValue)
-		} else if ad, err := t.getNamedArgValue(tkn.argName, args); err == nil {
-			buffer.Write(ad)
-		} else {
-			return nil, err
-		}
+	if err := t.renderTo(ctx, &buffer, resolver, validator); err != nil {
+		return nil, err
+	} else if err = validator.validate(buffer.Bytes()); err != nil {
+		return nil, err
 	}
 	return buffer.Bytes(), nil
 }
 
+// renderTo renders the template (using the specified arg resolver) into the writer
+func (t *jsonNamedTemplate) renderTo(ctx context.Context, w renderWriter, resolver ArgResolver, validator *outputValidator) error {
+	if resolver == nil {
+		resolver = MapArgs(nil)
+	}
+	collector := newArgErrorsCollector(t.collectErrors)
+	if err := t.checkUnknownArgs(resolver); err != nil {
+		if collector == nil {
+			return err
+		}
+		collector.add(unknownArgsKey, err)
+	}
+	w.Grow(t.fixedLens)
+	return renderTokens(ctx, w, t.tokens, nil, nil, t, resolver, validator, collector)
+}
+
+// RenderString produces a JSON string from the template using the specified arg resolver to resolve named args
+//
+// Missing named args (i.e. those not resolved by the resolver) are treated in the same way as for String
+func (t *jsonNamedTemplate) RenderString(resolver ArgResolver) (string, error) {
+	validator := newOutputValidator(t.validation)
+	var builder strings.Builder
+	if err := t.renderTo(context.Background(), &builder, resolver, validator); err != nil {
+		return "", err
+	}
+	result := builder.String()
+	if validator != nil || t.schema != nil {
+		// only copied to data when the output is validated...
+		data := []byte(result)
+		if err := validator.validate(data); err != nil {
+			return "", err
+		} else if err = t.schema.validate(data); err != nil {
+			return "", err
+		}
+	}
+	return result, nil
+}
+
 // ExpectedArgs returns a map of expected arg names - the boolean
 // value for each map entry indicates whether the template has a
 // default value for that named arg
@@ -182,22 +332,56 @@ func (t jsonNamedTemplate) ExpectedArgs() map[string]bool {
 	return result
 }
 
+// Args returns information about each arg marker in the template (see NamedTemplate.Args)
+func (t *jsonNamedTemplate) Args() []ArgInfo {
+	return copyArgInfos(t.argInfos)
+}
+
+// Schema generates a JSON Schema describing the output of the template (see NamedTemplate.Schema)
+func (t *jsonNamedTemplate) Schema() (map[string]interface{}, error) {
+	return t.tokens.schema(t.namedArgSchema)
+}
+
 // NewWith creates a new template with the args supplied being resolved in the new template
 //
 // Note: when resolving args into the new template, defaults are NOT used (but are copied over to the new)
 func (t *jsonNamedTemplate) NewWith(args map[string]interface{}) (NamedTemplate, error) {
+	return t.newWith(args, false)
+}
+
+// NewWithDefaults creates a new template with the args supplied being resolved in the new template - and, for
+// args not supplied, default values are also resolved (baked) into the new template
+//
+// Note: defaults that are not pre-encoded (e.g. lazy defaults - see LazyDefault) and env args are not resolved
+// into the new template
+func (t *jsonNamedTemplate) NewWithDefaults(args map[string]interface{}) (NamedTemplate, error) {
+	return t.newWith(args, true)
+}
+
+func (t *jsonNamedTemplate) newWith(args map[string]interface{}, useDefaults bool) (NamedTemplate, error) {
 	result := &jsonNamedTemplate{
 		argNames:         map[string]bool{},
 		tokens:           tokens{},
 		fixedLens:        t.fixedLens,
 		strict:           t.strict,
+		collectErrors:    t.collectErrors,
+		schema:           t.schema,
+		validation:       t.validation,
+		rejectUnknown:    t.rejectUnknown,
 		defaultArgValues: map[string]interface{}{},
+		envArgs:          t.envArgs,
+		envArgTypes:      t.envArgTypes,
+		filters:          t.filters,
 	}
 	for _, tkn := range t.tokens {
 		if tkn.fixed {
 			result.tokens = append(result.tokens, tkn)
-		} else if v, ok := args[tkn.argName]; ok {
-			if aData, err := argValueToData(v); err != nil {
+		} else if v, ok := newWithArg(tkn, MapArgs(args)); ok {
+			v, err := resolveLazyArg(context.Background(), v)
+			if err != nil {
+				return nil, newNamedArgMarshalError(tkn, err)
+			}
+			if aData, err := t.namedArgData(context.Background(), tkn, v); err != nil {
 				return nil, err
 			} else {
 				result.tokens = append(result.tokens, jsonTemplateToken{
@@ -206,91 +390,187 @@ func (t *jsonNamedTemplate) NewWith(args map[string]interface{}) (NamedTemplate,
 				})
 				result.fixedLens += len(aData)
 			}
+		} else if ed, ok := t.bakeableDefault(tkn); ok && useDefaults {
+			aData, err := t.encodedDefaultData(context.Background(), tkn, ed)
+			if err != nil {
+				return nil, err
+			}
+			result.tokens = append(result.tokens, jsonTemplateToken{
+				fixed:      true,
+				fixedValue: aData,
+			})
+			result.fixedLens += len(aData)
 		} else {
 			result.tokens = append(result.tokens, tkn)
-			result.argNames[tkn.argName] = true
-			if dv, ok := t.defaultArgValues[tkn.argName]; ok {
-				result.defaultArgValues[tkn.argName] = dv
+			for _, argName := range tkn.chainNames() {
+				result.argNames[argName] = true
+				if dv, ok := t.defaultArgValues[argName]; ok {
+					result.defaultArgValues[argName] = dv
+				}
 			}
 		}
 	}
 	result.tokens = result.tokens.joinContiguousFixed()
+	result.argInfos = result.tokens.argInfos()
 	return result, nil
 }
 
-// DefaultArgValue provides a default value for a specific named arg
+// newWithArg resolves a named arg token from the args supplied to NewWith (defaults are not used)
+func newWithArg(tkn jsonTemplateToken, args ArgResolver) (interface{}, bool) {
+	if len(tkn.fallbacks) == 0 {
+		return lookupNamedArg(tkn.argName, args)
+	}
+	for _, argName := range tkn.chainNames() {
+		if v, ok := lookupNamedArg(argName, args); ok && v != nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// DefaultArgValue returns a copy of the template with the default (see NamedTemplate.DefaultArgValue)
 func (t *jsonNamedTemplate) DefaultArgValue(argName string, value interface{}) NamedTemplate {
-	t.defaultArgValues[argName] = value
-	return t
+	result := t.clone()
+	result.setDefaultArgValue(argName, value)
+	return result
 }
 
-// DefaultArgValues provides default values for the specified named args
+// DefaultArgValues returns a copy of the template with the defaults (see NamedTemplate.DefaultArgValues)
 func (t *jsonNamedTemplate) DefaultArgValues(defaults map[string]interface{}) NamedTemplate {
+	result := t.clone()
 	for k, v := range defaults {
-		t.defaultArgValues[k] = v
+		result.setDefaultArgValue(k, v)
+	}
+	return result
+}
+
+// writeNamedArg resolves the value of a named arg marker and writes it to the writer - values that can write
+// directly to the output (e.g. bound templates) are written directly (unless the marker has filters)
+func (t *jsonNamedTemplate) writeNamedArg(ctx context.Context, w renderWriter, tkn jsonTemplateToken, args ArgResolver, validator *outputValidator) error {
+	v, err := t.getNamedArg(tkn, args)
+	if err != nil {
+		return err
+	}
+	switch vt := v.(type) {
+	case *encodedDefault:
+		data, err := t.encodedDefaultData(ctx, tkn, vt)
+		if err != nil {
+			return err
+		}
+		validator.add(tkn, w.Len())
+		w.Write(data)
+		return nil
+	case *dynamicDefault:
+		if v, err = vt.value(); err != nil {
+			return newNamedArgMarshalError(tkn, err)
+		}
+	}
+	if v, err = resolveLazyArg(ctx, v); err != nil {
+		return newNamedArgMarshalError(tkn, err)
+	}
+	if dw, ok := v.(dataWriter); ok && len(tkn.filters) == 0 {
+		validator.add(tkn, w.Len())
+		if err = dw.writeData(ctx, w); err != nil {
+			return newNamedArgMarshalError(tkn, err)
+		}
+		return nil
+	}
+	data, err := t.namedArgData(ctx, tkn, v)
+	if err != nil {
+		return err
 	}
-	return t
+	validator.add(tkn, w.Len())
+	w.Write(data)
+	return nil
 }
 
-func (t *jsonNamedTemplate) getNamedArgValue(argName string, args map[string]interface{}) ([]byte, error) {
-	if v, ok := args[argName]; ok {
-		return argValueToData(v)
-	} else if dv, dvok := t.defaultArgValues[argName]; dvok {
-		return argValueToData(dv)
-	} else if !ok && !t.strict {
-		return argValueToData(v)
+func (t *jsonNamedTemplate) namedArgData(ctx context.Context, tkn jsonTemplateToken, v interface{}) ([]byte, error) {
+	if len(tkn.filters) > 0 {
+		var err error
+		if v, err = t.applyFilters(tkn, v); err != nil {
+			return nil, err
+		}
 	}
-	return nil, fmt.Errorf("expected named arg '%s'", argName)
+	data, err := argValueToData(ctx, v)
+	if err == nil && t.validation == validateRawArgs {
+		err = checkRawArgData(v, data)
+	}
+	if err != nil {
+		return nil, newNamedArgMarshalError(tkn, err)
+	}
+	return data, nil
 }
 
-func (t *jsonNamedTemplate) parse(template string) error {
-	data := []byte(template)
-	l := len(data)
-	maxI := l - 1
-	t.lastTokenStart = 0
-	t.fixedLens = 0
-	for i := 0; i < l; i++ {
-		if data[i] == '?' {
-			if i < maxI && data[i+1] == '?' {
-				t.parseAddFixedToken(i+1, data)
-				i++
-				t.lastTokenStart = i + 1
-			} else {
-				if nameLen, err := t.parseAddArgToken(i, data); err == nil {
-					i += nameLen
-				} else {
-					return err
-				}
-			}
+func (t *jsonNamedTemplate) getNamedArg(tkn jsonTemplateToken, args ArgResolver) (interface{}, error) {
+	if len(tkn.fallbacks) > 0 {
+		return t.getChainedArg(tkn, args)
+	}
+	if v, ok, err := t.lookupArg(tkn.argName, args); err != nil {
+		return nil, newNamedArgMarshalError(tkn, err)
+	} else if ok {
+		return v, nil
+	} else if dv, dvok := t.defaultArgValues[tkn.argName]; dvok {
+		return dv, nil
+	} else if !t.strict {
+		return nil, nil
+	}
+	return nil, t.missingArgError(tkn, args)
+}
+
+// getChainedArg resolves the value for a named arg token that has fallback names (e.g. '?preferred?:fallback')
+//
+// The first present, non-nil arg is used - otherwise the first default value for any of the names is used
+func (t *jsonNamedTemplate) getChainedArg(tkn jsonTemplateToken, args ArgResolver) (interface{}, error) {
+	names := tkn.chainNames()
+	present := false
+	for _, argName := range names {
+		if v, ok, err := t.lookupArg(argName, args); err != nil {
+			return nil, newNamedArgMarshalError(tkn, err)
+		} else if ok && v != nil {
+			return v, nil
+		} else if ok {
+			present = true
 		}
 	}
-	t.parseAddFixedToken(l, data)
-	return nil
+	for _, argName := range names {
+		if dv, ok := t.defaultArgValues[argName]; ok {
+			return dv, nil
+		}
+	}
+	if present || !t.strict {
+		return nil, nil
+	}
+	return nil, newMissingArgError(tkn, "expected one of named args '%s'", strings.Join(names, "', '"))
+}
+
+func (t *jsonNamedTemplate) lookupArg(argName string, args ArgResolver) (interface{}, bool, error) {
+	if t.envArgs && isEnvArgName(argName) {
+		return lookupEnvArg(argName, t.envArgTypes)
+	}
+	v, ok := lookupNamedArg(argName, args)
+	return v, ok, nil
 }
 
-func (t *jsonNamedTemplate) parseAddFixedToken(i int, data []byte) {
-	if i > t.lastTokenStart {
-		t.tokens = append(t.tokens, jsonTemplateToken{
-			fixed:      true,
-			fixedValue: data[t.lastTokenStart:i],
-		})
-		t.fixedLens += i - t.lastTokenStart
+func (t *jsonNamedTemplate) missingArgError(tkn jsonTemplateToken, args ArgResolver) error {
+	argName := tkn.argName
+	if t.envArgs && isEnvArgName(argName) {
+		return newMissingArgError(tkn, "expected environment variable '%s' for named arg '%s'", argName[len(envArgPrefix):], argName)
+	} else if missingPath := namedArgMissingPath(argName, args); missingPath != "" {
+		return newMissingArgError(tkn, "named arg '%s' - path segment '%s' not found", argName, missingPath)
 	}
+	return newMissingArgError(tkn, "expected named arg '%s'", argName)
 }
 
-func (t *jsonNamedTemplate) parseAddArgToken(i int, data []byte) (int, error) {
-	t.parseAddFixedToken(i, data)
-	nameLen := scanForNameChars(i, data)
-	if nameLen == 0 {
-		return 0, fmt.Errorf("named token with no nameData at position %d", i)
+func (t *jsonNamedTemplate) parse(template string) error {
+	p, err := parseTemplate(template, false, true)
+	if err != nil {
+		return err
 	}
-	argName := string(data[i+1 : i+1+nameLen])
-	t.tokens = append(t.tokens, jsonTemplateToken{
-		argName: argName,
-	})
-	t.argNames[argName] = true
-	t.lastTokenStart = i + 1 + nameLen
-	return nameLen, nil
+	t.tokens = p.tokens
+	t.argInfos = t.tokens.argInfos()
+	t.fixedLens = p.fixedLens
+	t.argNames = p.argNames
+	return nil
 }
 
 func (t *jsonNamedTemplate) check() (err error) {
@@ -299,18 +579,41 @@ func (t *jsonNamedTemplate) check() (err error) {
 		for k := range t.argNames {
 			tArgs[k] = nil
 		}
-		tData, _ := t.Data(tArgs)
+		tData, _ := t.render(context.Background(), MapArgs(tArgs), nil)
 		var v interface{}
-		err = json.Unmarshal(tData, &v)
+		if err = json.Unmarshal(tData, &v); err != nil {
+			err = t.tokens.checkError(err)
+		}
 	}
 	return
 }
 
+// scanForFallbacks scans for fallback arg names following a named arg marker (e.g. '?preferred?:fallback') - where
+// i is the position immediately after the arg name
+//
+// returns the fallback arg names found and the total length of the fallbacks
+func scanForFallbacks(i int, data []byte) ([]string, int) {
+	var result []string
+	j := i
+	for j+2 < len(data) && data[j] == '?' && data[j+1] == ':' {
+		nameLen := scanForNameChars(j+1, data)
+		if nameLen == 0 {
+			break
+		}
+		result = append(result, string(data[j+2:j+2+nameLen]))
+		j += 2 + nameLen
+	}
+	return result, j - i
+}
+
 func scanForNameChars(i int, data []byte) int {
 	n := 0
 	for j := i + 1; j < len(data); j++ {
 		if isArgNameChar(data[j]) {
 			n++
+		} else if data[j] == '.' && n > 0 && j+1 < len(data) && isArgNameChar(data[j+1]) {
+			// dotted path separator (only when followed by further name chars)
+			n++
 		} else {
 			break
 		}
@@ -1,8 +1,12 @@
 package jsont
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"sync"
 	"testing"
 )
 
@@ -19,12 +23,12 @@ func TestNamedJsonTemplate(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, len(str), len(data))
 
-	jt.Options(OptionNonStrict)
+	jt = jt.Options(OptionNonStrict)
 	str, err = jt.String(map[string]interface{}{"foo": "aaa", "bar": "bbb"})
 	require.NoError(t, err)
 	require.Equal(t, `{"foo":"aaa","bar":"bbb","baz":"?","qux":null}`, str)
 
-	jt.Options(OptionStrict)
+	jt = jt.Options(OptionStrict)
 	_, err = jt.String(map[string]interface{}{"foo": "aaa", "bar": "bbb"})
 	require.Error(t, err)
 	require.Equal(t, "expected named arg 'qux'", err.Error())
@@ -32,7 +36,7 @@ func TestNamedJsonTemplate(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, "expected named arg 'qux'", err.Error())
 
-	jt.DefaultArgValue("qux", 2.2)
+	jt = jt.DefaultArgValue("qux", 2.2)
 	expArgs := jt.ExpectedArgs()
 	require.Equal(t, 3, len(expArgs))
 	require.False(t, expArgs["foo"])
@@ -45,7 +49,7 @@ func TestNamedJsonTemplate(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, str, string(data[:]))
 
-	jt.DefaultArgValues(map[string]interface{}{"foo": "xxx", "bar": "yyy", "qux": 3.3})
+	jt = jt.DefaultArgValues(map[string]interface{}{"foo": "xxx", "bar": "yyy", "qux": 3.3})
 	str, err = jt.String(map[string]interface{}{})
 	require.NoError(t, err)
 	require.Equal(t, `{"foo":"xxx","bar":"yyy","baz":"?","qux":3.3}`, str)
@@ -87,7 +91,7 @@ func TestNamedTemplateParseFailsWithBardArgName(t *testing.T) {
 
 func TestNamedTemplate_NewWith(t *testing.T) {
 	orig, err := NewNamedTemplate(`{"foo":?foo,"bar":?bar,"baz":"??","qux":?qux}`)
-	orig.DefaultArgValue("foo", "aaa")
+	orig = orig.DefaultArgValue("foo", "aaa")
 	require.NoError(t, err)
 	require.NotNil(t, orig)
 	require.Equal(t, 8, len((orig.(*jsonNamedTemplate)).tokens))
@@ -99,7 +103,7 @@ func TestNamedTemplate_NewWith(t *testing.T) {
 	require.Equal(t, 1, len((jt.(*jsonNamedTemplate)).defaultArgValues))
 	require.Equal(t, 2, len((jt.(*jsonNamedTemplate)).argNames))
 	require.Equal(t, 5, len((jt.(*jsonNamedTemplate)).tokens))
-	jt.Options(OptionNonStrict)
+	jt = jt.Options(OptionNonStrict)
 	str, err := jt.String(map[string]interface{}{})
 	require.NoError(t, err)
 	require.Equal(t, `{"foo":"aaa","bar":null,"baz":"?","qux":"ddd"}`, str)
@@ -128,3 +132,241 @@ func TestNewNamedTemplateWithErrorOption(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, "Fooey", err.Error())
 }
+
+func TestNamedTemplateFallbacks(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"name":?preferredName?:fullName?:userName|upper,"other":?other}`)
+	require.NoError(t, err)
+	expArgs := jt.ExpectedArgs()
+	require.Equal(t, 4, len(expArgs))
+	require.Contains(t, expArgs, "preferredName")
+	require.Contains(t, expArgs, "fullName")
+	require.Contains(t, expArgs, "userName")
+	tkn := (jt.(*jsonNamedTemplate)).tokens[1]
+	require.Equal(t, "preferredName", tkn.argName)
+	require.Equal(t, []string{"fullName", "userName"}, tkn.fallbacks)
+	require.Equal(t, 1, len(tkn.filters))
+
+	str, err := jt.String(map[string]interface{}{"preferredName": "aaa", "fullName": "bbb", "other": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"AAA","other":1}`, str)
+	str, err = jt.String(map[string]interface{}{"preferredName": nil, "fullName": "bbb", "other": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"BBB","other":1}`, str)
+	str, err = jt.String(map[string]interface{}{"userName": "ccc", "other": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"CCC","other":1}`, str)
+	str, err = jt.String(map[string]interface{}{"preferredName": nil, "other": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"name":null,"other":1}`, str)
+
+	_, err = jt.String(map[string]interface{}{"other": 1})
+	require.Error(t, err)
+	require.Equal(t, "expected one of named args 'preferredName', 'fullName', 'userName'", err.Error())
+
+	jt = jt.DefaultArgValue("userName", "default user")
+	str, err = jt.String(map[string]interface{}{"preferredName": nil, "other": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"DEFAULT USER","other":1}`, str)
+	jt = jt.DefaultArgValue("fullName", "default full")
+	str, err = jt.String(map[string]interface{}{"other": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"DEFAULT FULL","other":1}`, str)
+
+	jt, err = NewNamedTemplate(`{"name":?a?:b}`, OptionNonStrict)
+	require.NoError(t, err)
+	str, err = jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":null}`, str)
+}
+
+func TestNamedTemplateFallbacksNewWith(t *testing.T) {
+	orig, err := NewNamedTemplate(`{"name":?a?:b,"other":?c?:d}`)
+	require.NoError(t, err)
+	orig = orig.DefaultArgValue("d", "ddd")
+
+	jt, err := orig.NewWith(map[string]interface{}{"a": nil, "b": "bbb"})
+	require.NoError(t, err)
+	expArgs := jt.ExpectedArgs()
+	require.Equal(t, 2, len(expArgs))
+	require.False(t, expArgs["c"])
+	require.True(t, expArgs["d"])
+	str, err := jt.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"bbb","other":"ddd"}`, str)
+}
+
+func TestNamedTemplateFallbacksEscapes(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"name":?a??b}`, OptionUnChecked)
+	require.NoError(t, err)
+	str, err := jt.String(map[string]interface{}{"a": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"name":1?b}`, str)
+
+	_, err = NewNamedTemplate(`{"name":?a?:}`)
+	require.Error(t, err)
+	require.Equal(t, "named token with no nameData at position 10", err.Error())
+}
+
+func TestMixedTemplateFallbacks(t *testing.T) {
+	jt, err := NewMixedTemplate(`{"name":?a?:b,"other":?}`)
+	require.NoError(t, err)
+	named, positional := jt.ExpectedArgs()
+	require.Equal(t, 2, len(named))
+	require.Equal(t, 1, positional)
+	str, err := jt.String(map[string]interface{}{"b": "bbb"}, 1)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"bbb","other":1}`, str)
+}
+
+func TestNamedTemplateRenderContext(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"foo":?foo|upper,"bar":?bar}`, OptionDefaultArgValue("bar", func(ctx context.Context) (interface{}, error) {
+		return "default", nil
+	}))
+	require.NoError(t, err)
+	data, err := jt.RenderContext(context.Background(), MapArgs{"foo": func(ctx context.Context) (interface{}, error) {
+		return "aaa", nil
+	}})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"AAA","bar":"default"}`, string(data))
+
+	_, err = jt.RenderContext(context.Background(), MapArgs{"foo": func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("fooey")
+	}})
+	require.Error(t, err)
+	require.Equal(t, "named arg 'foo' at line 1, column 8: fooey", err.Error())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	called := false
+	_, err = jt.RenderContext(ctx, MapArgs{
+		"foo": func(ctx context.Context) (interface{}, error) {
+			cancel()
+			return "aaa", nil
+		},
+		"bar": func(ctx context.Context) (interface{}, error) {
+			called = true
+			return "bbb", nil
+		},
+	})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.Canceled))
+	require.False(t, called)
+
+	// cancelled context also stops collecting errors...
+	jt, err = NewNamedTemplate(`{"foo":?foo,"bar":?bar}`, OptionCollectErrors)
+	require.NoError(t, err)
+	_, err = jt.RenderContext(ctx, nil)
+	require.Error(t, err)
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestNamedTemplateNewWithLazyArgs(t *testing.T) {
+	jt := MustCompileNamedTemplate(`{"foo":?foo|upper,"bar":?bar}`)
+	jt2, err := jt.NewWith(map[string]interface{}{"foo": func(ctx context.Context) (interface{}, error) {
+		return "aaa", nil
+	}})
+	require.NoError(t, err)
+	str, err := jt2.String(map[string]interface{}{"bar": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"AAA","bar":1}`, str)
+
+	_, err = jt.NewWith(map[string]interface{}{"bar": func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("fooey")
+	}})
+	require.Error(t, err)
+	require.Equal(t, "named arg 'bar' at line 1, column 25: fooey", err.Error())
+}
+
+func TestNamedTemplateCopyOnWrite(t *testing.T) {
+	jt := MustCompileNamedTemplate(`{"a":?a,"b":?b}`, OptionDefaultArgValue("a", 1))
+	jt2 := jt.DefaultArgValue("b", 2)
+	jt3 := jt2.DefaultArgValues(map[string]interface{}{"a": 3})
+	jt4 := jt.Options(OptionDefaultArgValue("b", 4))
+	require.NotSame(t, jt, jt2)
+	require.NotSame(t, jt2, jt3)
+	require.NotSame(t, jt, jt4)
+
+	_, err := jt.String(nil)
+	require.Error(t, err)
+	str, err := jt2.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":2}`, str)
+	str, err = jt3.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":3,"b":2}`, str)
+	str, err = jt4.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":4}`, str)
+	require.Equal(t, 1, len((jt.(*jsonNamedTemplate)).defaultArgValues))
+	require.NotSame(t, jt, jt.Options(OptionNonStrict))
+	_, err = jt.String(nil)
+	require.Error(t, err)
+}
+
+func TestTemplatesConcurrentUse(t *testing.T) {
+	// run with -race to detect data races...
+	named := MustCompileNamedTemplate(`{"a":?a,"b":?b}`, OptionDefaultArgValue("b", "b"))
+	mixed := MustCompileMixedTemplate(`{"a":?a,"b":?}`)
+	positional := MustCompileTemplate(`{"a":?,"b":?}`)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if i%2 == 0 {
+					str, err := named.String(map[string]interface{}{"a": j})
+					assert.NoError(t, err)
+					assert.Equal(t, fmt.Sprintf(`{"a":%d,"b":"b"}`, j), str)
+					_, err = mixed.Data(map[string]interface{}{"a": j}, j)
+					assert.NoError(t, err)
+					_, err = positional.Data(j, j)
+					assert.NoError(t, err)
+				} else {
+					other := named.DefaultArgValue("b", j).DefaultArgValues(map[string]interface{}{"a": i})
+					str, err := other.String(nil)
+					assert.NoError(t, err)
+					assert.Equal(t, fmt.Sprintf(`{"a":%d,"b":%d}`, i, j), str)
+					str, err = mixed.DefaultArgValue("a", j).Options(OptionNonStrict).String(nil)
+					assert.NoError(t, err)
+					assert.Equal(t, fmt.Sprintf(`{"a":%d,"b":null}`, j), str)
+					_, err = positional.Options(OptionNonStrict, OptionCollectErrors).String()
+					assert.NoError(t, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestNamedTemplateCloneAndWith(t *testing.T) {
+	jt := MustCompileNamedTemplate(`{"a":?a,"b":?b}`, OptionDefaultArgValue("a", 1))
+	clone := jt.Clone()
+	require.NotSame(t, jt, clone)
+	clone = clone.DefaultArgValue("b", 2)
+	_, err := jt.String(nil)
+	require.Error(t, err)
+	str, err := clone.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":2}`, str)
+
+	jt2, err := jt.With(OptionNonStrict, OptionDefaultArgValue("a", 3))
+	require.NoError(t, err)
+	require.Equal(t, &(jt.(*jsonNamedTemplate)).tokens[0], &(jt2.(*jsonNamedTemplate)).tokens[0])
+	str, err = jt2.String(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":3,"b":null}`, str)
+	str, err = jt.String(map[string]interface{}{"b": 2})
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":2}`, str)
+
+	require.NotSame(t, jt2, jt2.Clone())
+
+	_, err = jt.With(&badOption{})
+	require.Error(t, err)
+}
+
+type badOption struct{}
+
+func (o *badOption) Apply(on any) error {
+	return errors.New("bad option")
+}
@@ -9,6 +9,8 @@ type Option interface {
 var (
 	_OptionChecked         = &optionChecked{true}
 	_OptionUnChecked       = &optionChecked{false}
+	_OptionCollectErrors   = &optionCollectErrors{true}
+	_OptionFailFast        = &optionCollectErrors{false}
 	_OptionStrict          = &optionStrict{true}
 	_OptionNonStrict       = &optionStrict{false}
 	_OptionDefaultArgValue = func(name string, value interface{}) Option {
@@ -22,6 +24,26 @@ var (
 			defaults: defaults,
 		}
 	}
+	_OptionFilters = func(filters map[string]FilterFunc) Option {
+		return &optionFilters{
+			filters: filters,
+		}
+	}
+	_OptionEnvArgs     = &optionEnvArgs{}
+	_OptionEnvArgTypes = func(types map[string]EnvArgType) Option {
+		return &optionEnvArgs{
+			types: types,
+		}
+	}
+	_OptionRejectUnknownArgs = &optionRejectUnknownArgs{true}
+	_OptionAllowUnknownArgs  = &optionRejectUnknownArgs{false}
+	_OptionValidateOutput    = &optionValidation{validateOutput}
+	_OptionValidateRawArgs   = &optionValidation{validateRawArgs}
+	_OptionSchema            = func(schemaJSON []byte) Option {
+		return &optionSchema{
+			schemaJSON: schemaJSON,
+		}
+	}
 )
 
 var (
@@ -31,6 +53,55 @@ var (
 	OptionNonStrict        Option = _OptionNonStrict
 	OptionDefaultArgValue         = _OptionDefaultArgValue
 	OptionDefaultArgValues        = _OptionDefaultArgValues
+
+	// OptionFilters adds custom filters that can be used on named arg markers (e.g. '?name|myFilter:arg')
+	//
+	// Custom filters take precedence over built-in filters of the same name - the built-in filters are:
+	//   upper, lower, trim - string case and whitespace
+	//   trunc:n - truncates strings to n characters
+	//   string - converts the value to a string
+	//   rfc3339, date, unix - formats time.Time values
+	//   cents - converts integer cents to a decimal number (e.g. 1234 -> 12.34)
+	OptionFilters = _OptionFilters
+	// OptionEnvArgs enables resolving named args prefixed with 'env.' (e.g. '?env.DATABASE_URL')
+	// from environment variables
+	//
+	// Missing environment variables are treated in the same way as missing named args (i.e. defaults
+	// and strictness apply)
+	OptionEnvArgs Option = _OptionEnvArgs
+	// OptionEnvArgTypes enables resolving named args from environment variables (as OptionEnvArgs) and
+	// provides type coercion hints for specific environment variables - e.g.
+	//   OptionEnvArgTypes(map[string]jsont.EnvArgType{"PORT": jsont.EnvInt, "DEBUG": jsont.EnvBool})
+	OptionEnvArgTypes = _OptionEnvArgTypes
+	// OptionCollectErrors makes rendering collect all missing and invalid args into a single *ArgErrors error
+	// (rather than returning on the first error)
+	OptionCollectErrors Option = _OptionCollectErrors
+	// OptionFailFast makes rendering return on the first missing or invalid arg (the default)
+	OptionFailFast Option = _OptionFailFast
+	// OptionRejectUnknownArgs makes rendering a named template return an error when the args map supplied
+	// contains names that are not expected by the template (the error includes suggestions for
+	// likely misspelt arg names)
+	OptionRejectUnknownArgs Option = _OptionRejectUnknownArgs
+	// OptionAllowUnknownArgs allows the args map supplied to contain names that are not expected by the template (the default)
+	OptionAllowUnknownArgs Option = _OptionAllowUnknownArgs
+	// OptionSchema makes rendering validate the output document against the supplied JSON Schema (draft 2020-12) - an
+	// output that does not validate results in a *SchemaError (indicating the JSON Pointer of the failing location)
+	//
+	// Unlike OptionChecked (which checks the template is well-formed once, at compile time), the validation is
+	// performed on every render
+	//
+	// Schemas using keywords that are not supported (e.g. format, contains or a non-local '$ref') or with patterns that do not
+	// compile are rejected when the option is applied
+	OptionSchema = _OptionSchema
+	// OptionValidateOutput makes rendering verify that each rendered document is valid JSON (e.g. where raw []byte,
+	// json.RawMessage or NameValues args are spliced into the output) - an invalid document results in an
+	// *InvalidOutputError (indicating the arg rendered before the invalid JSON)
+	OptionValidateOutput Option = _OptionValidateOutput
+	// OptionValidateRawArgs makes rendering verify that each raw arg value ([]byte, json.RawMessage, NameValues or DataProvider) is
+	// valid JSON - an invalid raw arg value results in an *ArgMarshalError
+	//
+	// This is cheaper than OptionValidateOutput (as only the raw arg values are checked)
+	OptionValidateRawArgs Option = _OptionValidateRawArgs
 )
 
 type optionChecked struct {
@@ -43,6 +114,83 @@ func (o *optionChecked) Apply(on any) error {
 		ont.checkReqd = o.checkReqd
 	case *jsonNamedTemplate:
 		ont.checkReqd = o.checkReqd
+	case *jsonMixedTemplate:
+		ont.checkReqd = o.checkReqd
+	}
+	return nil
+}
+
+type optionCollectErrors struct {
+	collect bool
+}
+
+func (o *optionCollectErrors) Apply(on any) error {
+	switch ont := on.(type) {
+	case *jsonTemplate:
+		ont.collectErrors = o.collect
+	case *jsonNamedTemplate:
+		ont.collectErrors = o.collect
+	case *jsonMixedTemplate:
+		ont.collectErrors = o.collect
+	default:
+		return fmt.Errorf("option CollectErrors cannot be applied to type '%T'", on)
+	}
+	return nil
+}
+
+type optionRejectUnknownArgs struct {
+	reject bool
+}
+
+func (o *optionRejectUnknownArgs) Apply(on any) error {
+	switch ont := on.(type) {
+	case *jsonNamedTemplate:
+		ont.rejectUnknown = o.reject
+		return nil
+	case *jsonMixedTemplate:
+		ont.named.rejectUnknown = o.reject
+		return nil
+	}
+	return fmt.Errorf("option RejectUnknownArgs cannot be applied to type '%T'", on)
+}
+
+type optionValidation struct {
+	validation jsonValidation
+}
+
+func (o *optionValidation) Apply(on any) error {
+	switch ont := on.(type) {
+	case *jsonTemplate:
+		ont.validation = o.validation
+	case *jsonNamedTemplate:
+		ont.validation = o.validation
+	case *jsonMixedTemplate:
+		ont.validation = o.validation
+		ont.named.validation = o.validation
+	default:
+		return fmt.Errorf("option Validate cannot be applied to type '%T'", on)
+	}
+	return nil
+}
+
+type optionSchema struct {
+	schemaJSON []byte
+}
+
+func (o *optionSchema) Apply(on any) error {
+	schema, err := compileSchema(o.schemaJSON)
+	if err != nil {
+		return fmt.Errorf("option Schema - invalid schema: %w", err)
+	}
+	switch ont := on.(type) {
+	case *jsonTemplate:
+		ont.schema = schema
+	case *jsonNamedTemplate:
+		ont.schema = schema
+	case *jsonMixedTemplate:
+		ont.schema = schema
+	default:
+		return fmt.Errorf("option Schema cannot be applied to type '%T'", on)
 	}
 	return nil
 }
@@ -59,6 +207,9 @@ func (o *optionStrict) Apply(on any) error {
 	case *jsonTemplate:
 		ont.strict = o.strict
 		return nil
+	case *jsonMixedTemplate:
+		ont.setStrict(o.strict)
+		return nil
 	}
 	return fmt.Errorf("option Strict cannot be applied to type '%T'", on)
 }
@@ -69,11 +220,15 @@ type optionDefaultArgValue struct {
 }
 
 func (o *optionDefaultArgValue) Apply(on any) error {
-	if ont, ok := on.(NamedTemplate); ok {
-		ont.DefaultArgValue(o.name, o.value)
-		return nil
+	switch ont := on.(type) {
+	case *jsonNamedTemplate:
+		ont.setDefaultArgValue(o.name, o.value)
+	case *jsonMixedTemplate:
+		ont.named.setDefaultArgValue(o.name, o.value)
+	default:
+		return fmt.Errorf("option OptionDefaultArgValue cannot be applied to type '%T'", on)
 	}
-	return fmt.Errorf("option OptionDefaultArgValue cannot be applied to type '%T'", on)
+	return nil
 }
 
 type optionDefaultArgValues struct {
@@ -81,9 +236,74 @@ type optionDefaultArgValues struct {
 }
 
 func (o *optionDefaultArgValues) Apply(on any) error {
-	if ont, ok := on.(NamedTemplate); ok {
-		ont.DefaultArgValues(o.defaults)
+	switch ont := on.(type) {
+	case *jsonNamedTemplate:
+		for k, v := range o.defaults {
+			ont.setDefaultArgValue(k, v)
+		}
+	case *jsonMixedTemplate:
+		for k, v := range o.defaults {
+			ont.named.setDefaultArgValue(k, v)
+		}
+	default:
+		return fmt.Errorf("option OptionDefaultArgValues cannot be applied to type '%T'", on)
+	}
+	return nil
+}
+
+type optionEnvArgs struct {
+	types map[string]EnvArgType
+}
+
+func (o *optionEnvArgs) Apply(on any) error {
+	switch ont := on.(type) {
+	case *jsonNamedTemplate:
+		o.applyTo(ont)
+		return nil
+	case *jsonMixedTemplate:
+		o.applyTo(ont.named)
+		return nil
+	}
+	return fmt.Errorf("option OptionEnvArgs cannot be applied to type '%T'", on)
+}
+
+func (o *optionEnvArgs) applyTo(t *jsonNamedTemplate) {
+	t.envArgs = true
+	if len(o.types) > 0 {
+		types := make(map[string]EnvArgType, len(t.envArgTypes)+len(o.types))
+		for k, v := range t.envArgTypes {
+			types[k] = v
+		}
+		for k, v := range o.types {
+			types[k] = v
+		}
+		t.envArgTypes = types
+	}
+}
+
+type optionFilters struct {
+	filters map[string]FilterFunc
+}
+
+func (o *optionFilters) Apply(on any) error {
+	switch ont := on.(type) {
+	case *jsonNamedTemplate:
+		o.applyTo(ont)
 		return nil
+	case *jsonMixedTemplate:
+		o.applyTo(ont.named)
+		return nil
+	}
+	return fmt.Errorf("option OptionFilters cannot be applied to type '%T'", on)
+}
+
+func (o *optionFilters) applyTo(t *jsonNamedTemplate) {
+	filters := make(map[string]FilterFunc, len(t.filters)+len(o.filters))
+	for k, v := range t.filters {
+		filters[k] = v
+	}
+	for k, v := range o.filters {
+		filters[k] = v
 	}
-	return fmt.Errorf("option OptionDefaultArgValues cannot be applied to type '%T'", on)
+	t.filters = filters
 }
@@ -0,0 +1,180 @@
+package jsont
+
+import (
+	"strconv"
+)
+
+// templateParser parses a template string into tokens - the same parser is used by all template types, with
+// positional arg markers ('?' and '?1') and/or named arg markers ('?name') being accepted
+type templateParser struct {
+	positional     bool
+	named          bool
+	tokens         tokens
+	fixedLens      int
+	argsCount      int
+	argNames       map[string]bool
+	lastTokenStart int
+	nextArgIndex   int
+	plainArgs      bool
+	indexedArgs    bool
+}
+
+// parseTemplate parses the template string - positional and named determine which arg markers are accepted
+func parseTemplate(template string, positional bool, named bool) (*templateParser, error) {
+	p := &templateParser{
+		positional: positional,
+		named:      named,
+		tokens:     make(tokens, 0),
+		argNames:   map[string]bool{},
+	}
+	data := []byte(template)
+	l := len(data)
+	maxI := l - 1
+	for i := 0; i < l; i++ {
+		if data[i] == '?' {
+			if i < maxI && data[i+1] == '?' {
+				p.addFixedToken(i+1, data)
+				i++
+				p.lastTokenStart = i + 1
+			} else if markerLen, err := p.addArgToken(i, data); err == nil {
+				i += markerLen
+			} else {
+				return nil, err
+			}
+		}
+	}
+	p.addFixedToken(l, data)
+	p.tokens.setPositions(data)
+	if p.indexedArgs {
+		if err := p.checkArgIndices(data); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *templateParser) addFixedToken(i int, data []byte) {
+	if i > p.lastTokenStart {
+		p.tokens = append(p.tokens, jsonTemplateToken{
+			fixed:      true,
+			fixedValue: data[p.lastTokenStart:i],
+			pos:        Position{Offset: p.lastTokenStart},
+		})
+		p.fixedLens += i - p.lastTokenStart
+	}
+}
+
+// addArgToken adds the token for the arg marker at position i - returns the length of the marker (excluding the '?')
+func (p *templateParser) addArgToken(i int, data []byte) (int, error) {
+	p.addFixedToken(i, data)
+	var markerLen int
+	var err error
+	if p.positional && (!p.named || isIndexMarker(i, data)) {
+		markerLen, err = p.addPositionalArgToken(i, data)
+	} else {
+		markerLen, err = p.addNamedArgToken(i, data)
+	}
+	if err != nil {
+		return 0, err
+	}
+	p.lastTokenStart = i + 1 + markerLen
+	return markerLen, nil
+}
+
+func (p *templateParser) addPositionalArgToken(i int, data []byte) (int, error) {
+	indexLen := scanForIndexChars(i, data)
+	if (indexLen > 0 && p.plainArgs) || (indexLen == 0 && p.indexedArgs) {
+		return 0, newSyntaxError(data, i, nil, "cannot mix '?' and indexed '?n' positional args at position %d", i)
+	}
+	argIndex := p.nextArgIndex
+	if indexLen > 0 {
+		if idx, err := parseArgIndex(i, data); err == nil {
+			argIndex = idx
+		} else {
+			return 0, err
+		}
+		p.indexedArgs = true
+	} else {
+		p.nextArgIndex++
+		p.plainArgs = true
+	}
+	p.tokens = append(p.tokens, jsonTemplateToken{
+		argIndex: argIndex,
+		pos:      Position{Offset: i},
+	})
+	if argIndex >= p.argsCount {
+		p.argsCount = argIndex + 1
+	}
+	return indexLen, nil
+}
+
+func (p *templateParser) addNamedArgToken(i int, data []byte) (int, error) {
+	nameLen := scanForNameChars(i, data)
+	if nameLen == 0 {
+		return 0, newSyntaxError(data, i, nil, "named token with no nameData at position %d", i)
+	}
+	argName := string(data[i+1 : i+1+nameLen])
+	fallbacks, fallbacksLen := scanForFallbacks(i+1+nameLen, data)
+	filters, filtersLen := scanForFilters(i+1+nameLen+fallbacksLen, data)
+	tkn := jsonTemplateToken{
+		argName:   argName,
+		fallbacks: fallbacks,
+		filters:   filters,
+		pos:       Position{Offset: i},
+	}
+	p.tokens = append(p.tokens, tkn)
+	for _, name := range tkn.chainNames() {
+		p.argNames[name] = true
+	}
+	return nameLen + fallbacksLen + filtersLen, nil
+}
+
+// checkArgIndices checks that indexed positional args are contiguous (i.e. every index from '?1' up to the
+// highest index used is used) - so that an index cannot be skipped
+func (p *templateParser) checkArgIndices(data []byte) error {
+	used := map[int]bool{}
+	for _, tkn := range p.tokens {
+		if !tkn.fixed && tkn.argName == "" {
+			used[tkn.argIndex] = true
+		}
+	}
+	if len(used) == p.argsCount {
+		return nil
+	}
+	missing := 0
+	for used[missing] {
+		missing++
+	}
+	for _, tkn := range p.tokens {
+		if !tkn.fixed && tkn.argName == "" && tkn.argIndex > missing {
+			return newSyntaxError(data, tkn.pos.Offset, nil, "arg index '%d' at position %d skips unused arg index '%d'", tkn.argIndex+1, tkn.pos.Offset, missing+1)
+		}
+	}
+	return nil
+}
+
+// isIndexMarker determines whether the arg marker at position i is positional (i.e. a plain '?' or an indexed '?1')
+// in a template that also has named args - where the marker is positional if there are no name chars following
+// the '?' or all the name chars are digits
+func isIndexMarker(i int, data []byte) bool {
+	nameLen := scanForNameChars(i, data)
+	return nameLen == 0 || scanForIndexChars(i, data) == nameLen
+}
+
+func scanForIndexChars(i int, data []byte) int {
+	n := 0
+	for j := i + 1; j < len(data) && data[j] >= '0' && data[j] <= '9'; j++ {
+		n++
+	}
+	return n
+}
+
+// parseArgIndex converts the 1 based index digits of an indexed arg marker (at position i) to a 0 based arg index
+func parseArgIndex(i int, data []byte) (int, error) {
+	digits := data[i+1 : i+1+scanForIndexChars(i, data)]
+	idx, err := strconv.Atoi(string(digits))
+	if err != nil || idx < 1 {
+		return 0, newSyntaxError(data, i, err, "invalid arg index '%s' at position %d", digits, i)
+	}
+	return idx - 1, nil
+}
@@ -0,0 +1,36 @@
+package jsont
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	testCases := []struct {
+		template   string
+		positional bool
+		named      bool
+		expectArgs int
+		expectName map[string]bool
+		expectTkns int
+	}{
+		{`[?1,"??",?2]`, true, false, 2, map[string]bool{}, 6},
+		{`[?foo]`, true, false, 1, map[string]bool{}, 3},
+		{`[?foo?:bar|upper,"??"]`, false, true, 0, map[string]bool{"foo": true, "bar": true}, 4},
+		{`[?foo,?1,?2,?1x]`, true, true, 2, map[string]bool{"foo": true, "1x": true}, 9},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			p, err := parseTemplate(tc.template, tc.positional, tc.named)
+			require.NoError(t, err, "test case %d", i)
+			require.Equal(t, tc.expectArgs, p.argsCount)
+			require.Equal(t, tc.expectName, p.argNames)
+			require.Equal(t, tc.expectTkns, len(p.tokens))
+		})
+	}
+
+	_, err := parseTemplate(`[?]`, false, true)
+	require.Error(t, err)
+	_, err = parseTemplate(`[?0]`, true, true)
+	require.Error(t, err)
+}
@@ -0,0 +1,91 @@
+package jsont
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// lookupNamedArg looks up a named arg using the supplied arg resolver
+//
+// If the arg name is a dotted path (e.g. "user.address.city") and there is no arg with
+// that exact name, the path is walked from the first segment arg - through nested maps,
+// structs (using json tag names) and slices/arrays (using numeric segments)
+func lookupNamedArg(argName string, args ArgResolver) (interface{}, bool) {
+	v, ok, _ := walkNamedArgPath(argName, args)
+	return v, ok
+}
+
+// namedArgMissingPath returns the missing path (up to and including the missing segment) of a dotted path
+// named arg - or an empty string if the first segment arg is not present (or the arg is not a dotted path)
+func namedArgMissingPath(argName string, args ArgResolver) string {
+	if _, ok, at := walkNamedArgPath(argName, args); !ok && at > 0 {
+		return strings.Join(strings.Split(argName, ".")[:at+1], ".")
+	}
+	return ""
+}
+
+// walkNamedArgPath resolves the named arg (or walks the dotted path) - when not found, the
+// index of the path segment that could not be resolved is also returned
+func walkNamedArgPath(argName string, args ArgResolver) (interface{}, bool, int) {
+	if v, ok := args.Resolve(argName); ok {
+		return v, true, 0
+	}
+	segments := strings.Split(argName, ".")
+	if len(segments) < 2 {
+		return nil, false, 0
+	}
+	v, ok := args.Resolve(segments[0])
+	if !ok {
+		return nil, false, 0
+	}
+	for i, segment := range segments[1:] {
+		if v, ok = pathSegmentValue(v, segment); !ok {
+			return nil, false, i + 1
+		}
+	}
+	return v, true, 0
+}
+
+func pathSegmentValue(v interface{}, segment string) (interface{}, bool) {
+	if m, ok := v.(map[string]interface{}); ok {
+		sv, sok := m[segment]
+		return sv, sok
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			if mv := rv.MapIndex(reflect.ValueOf(segment).Convert(rv.Type().Key())); mv.IsValid() {
+				return mv.Interface(), true
+			}
+		}
+	case reflect.Struct:
+		if fv, ok := structFieldByJsonName(rv, segment); ok {
+			return fv.Interface(), true
+		}
+	case reflect.Slice, reflect.Array:
+		if idx, err := strconv.Atoi(segment); err == nil && idx >= 0 && idx < rv.Len() {
+			return rv.Index(idx).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// structFieldByJsonName finds the struct field with the json name - following the same rules as encoding/json (see
+// structFields), i.e. fields promoted from embedded structs are found and, where more than one field has the name, the
+// dominant field is used
+func structFieldByJsonName(rv reflect.Value, name string) (reflect.Value, bool) {
+	for _, fld := range structFields(rv.Type()) {
+		if fld.name == name {
+			return structFieldValue(rv, fld.index)
+		}
+	}
+	return reflect.Value{}, false
+}
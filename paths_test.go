@@ -0,0 +1,169 @@
+package jsont
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type testAddress struct {
+	City     string `json:"city"`
+	Postcode string `json:"postcode,omitempty"`
+	Hidden   string `json:"-"`
+	Country  string
+	internal string
+}
+
+type testUser struct {
+	Name    string       `json:"name"`
+	Address *testAddress `json:"address"`
+	Tags    []string     `json:"tags"`
+	testEmbedded
+}
+
+type testEmbedded struct {
+	Role string `json:"role"`
+}
+
+func TestNamedTemplateDottedPaths(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"city":?user.address.city,"tag":?user.tags.1,"role":?user.role,"country":?user.address.Country}`)
+	require.NoError(t, err)
+	expArgs := jt.ExpectedArgs()
+	require.Equal(t, 4, len(expArgs))
+	require.False(t, expArgs["user.address.city"])
+
+	user := &testUser{
+		Name:         "Bilbo",
+		Address:      &testAddress{City: "Hobbiton", Country: "Shire"},
+		Tags:         []string{"hobbit", "burglar"},
+		testEmbedded: testEmbedded{Role: "ring-bearer"},
+	}
+	str, err := jt.String(map[string]interface{}{"user": user})
+	require.NoError(t, err)
+	require.Equal(t, `{"city":"Hobbiton","tag":"burglar","role":"ring-bearer","country":"Shire"}`, str)
+
+	str, err = jt.String(map[string]interface{}{
+		"user": map[string]interface{}{
+			"address": map[string]string{"city": "Bree", "Country": "Eriador"},
+			"tags":    [2]string{"a", "b"},
+			"role":    "innkeeper",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, `{"city":"Bree","tag":"b","role":"innkeeper","country":"Eriador"}`, str)
+
+	// exact arg names take precedence...
+	str, err = jt.String(map[string]interface{}{"user": user, "user.address.city": "Rivendell"})
+	require.NoError(t, err)
+	require.Equal(t, `{"city":"Rivendell","tag":"burglar","role":"ring-bearer","country":"Shire"}`, str)
+}
+
+func TestNamedTemplateDottedPathErrors(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"city":?user.address.city}`)
+	require.NoError(t, err)
+
+	_, err = jt.String(map[string]interface{}{})
+	require.Error(t, err)
+	require.Equal(t, "expected named arg 'user.address.city'", err.Error())
+
+	_, err = jt.String(map[string]interface{}{"user": map[string]interface{}{}})
+	require.Error(t, err)
+	require.Equal(t, "named arg 'user.address.city' - path segment 'user.address' not found", err.Error())
+
+	_, err = jt.String(map[string]interface{}{"user": &testUser{}})
+	require.Error(t, err)
+	require.Equal(t, "named arg 'user.address.city' - path segment 'user.address.city' not found", err.Error())
+
+	_, err = jt.String(map[string]interface{}{"user": &testUser{Address: &testAddress{}}})
+	require.NoError(t, err)
+
+	jt = jt.DefaultArgValue("user.address.city", "Unknown")
+	str, err := jt.String(map[string]interface{}{"user": &testUser{}})
+	require.NoError(t, err)
+	require.Equal(t, `{"city":"Unknown"}`, str)
+
+	jt, err = NewNamedTemplate(`{"city":?user.address.city}`, OptionNonStrict)
+	require.NoError(t, err)
+	str, err = jt.String(map[string]interface{}{"user": "not walkable"})
+	require.NoError(t, err)
+	require.Equal(t, `{"city":null}`, str)
+}
+
+func TestNamedTemplateDottedPathNewWith(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"city":?user.address.city,"name":?user.name}`)
+	require.NoError(t, err)
+	jt, err = jt.NewWith(map[string]interface{}{"user": &testUser{Name: "Frodo"}})
+	require.NoError(t, err)
+	expArgs := jt.ExpectedArgs()
+	require.Equal(t, 1, len(expArgs))
+	str, err := jt.String(map[string]interface{}{"user.address.city": "Bag End"})
+	require.NoError(t, err)
+	require.Equal(t, `{"city":"Bag End","name":"Frodo"}`, str)
+}
+
+type testPathEmbeddedName struct {
+	Name  string
+	Other string
+}
+
+type testPathDominant struct {
+	testPathEmbeddedName
+	Name string
+}
+
+func TestNamedTemplateDottedPathDominantField(t *testing.T) {
+	o := testPathDominant{
+		testPathEmbeddedName: testPathEmbeddedName{Name: "embedded", Other: "other"},
+		Name:                 "outer",
+	}
+	jt := MustCompileNamedTemplate(`{"name":?o.Name,"other":?o.Other}`)
+	str, err := jt.String(map[string]interface{}{"o": o})
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"outer","other":"other"}`, str)
+	data, err := json.Marshal(o)
+	require.NoError(t, err)
+	require.Equal(t, `{"Other":"other","Name":"outer"}`, string(data))
+}
+
+func TestScanForNameCharsWithDots(t *testing.T) {
+	testCases := []struct {
+		template string
+		expect   int
+	}{
+		{`?foo`, 3},
+		{`?foo.bar`, 7},
+		{`?foo.`, 3},
+		{`?foo..bar`, 3},
+		{`?.foo`, 0},
+		{`?foo.0.bar}`, 9},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			require.Equal(t, tc.expect, scanForNameChars(0, []byte(tc.template)))
+		})
+	}
+}
+
+func TestPathSegmentValue(t *testing.T) {
+	var nilPtr *testUser
+	_, ok := pathSegmentValue(nilPtr, "name")
+	require.False(t, ok)
+	_, ok = pathSegmentValue(nil, "name")
+	require.False(t, ok)
+	_, ok = pathSegmentValue(map[int]string{1: "a"}, "1")
+	require.False(t, ok)
+	_, ok = pathSegmentValue([]string{"a"}, "1")
+	require.False(t, ok)
+	_, ok = pathSegmentValue([]string{"a"}, "x")
+	require.False(t, ok)
+	_, ok = pathSegmentValue(testAddress{Hidden: "x"}, "Hidden")
+	require.False(t, ok)
+	_, ok = pathSegmentValue(testAddress{internal: "x"}, "internal")
+	require.False(t, ok)
+	v, ok := pathSegmentValue(testAddress{Postcode: "x"}, "postcode")
+	require.True(t, ok)
+	require.Equal(t, "x", v)
+	v, ok = pathSegmentValue(&testUser{testEmbedded: testEmbedded{Role: "r"}}, "role")
+	require.True(t, ok)
+	require.Equal(t, "r", v)
+}
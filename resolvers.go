@@ -0,0 +1,79 @@
+package jsont
+
+import (
+	"net/url"
+	"os"
+)
+
+// ArgResolver is the interface used by named templates to resolve named arg values
+type ArgResolver interface {
+	// Resolve returns the value for the named arg - and whether the named arg is present
+	Resolve(name string) (interface{}, bool)
+}
+
+// MapArgs is an ArgResolver that resolves named args from a map
+type MapArgs map[string]interface{}
+
+// Resolve returns the value for the named arg - and whether the named arg is present
+func (m MapArgs) Resolve(name string) (interface{}, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+// URLValuesArgs is an ArgResolver that resolves named args from url.Values
+//
+// Where a named arg has a single value, the value is resolved as a string - where a named
+// arg has multiple values, the value is resolved as a []string
+type URLValuesArgs url.Values
+
+// Resolve returns the value for the named arg - and whether the named arg is present
+func (u URLValuesArgs) Resolve(name string) (interface{}, bool) {
+	if vs, ok := u[name]; ok && len(vs) > 0 {
+		if len(vs) == 1 {
+			return vs[0], true
+		}
+		return vs, true
+	}
+	return nil, false
+}
+
+// EnvArgs returns an ArgResolver that resolves named args from environment variables
+//
+// The prefix (if any) is prepended to the arg name to determine the environment variable name - e.g.
+//   EnvArgs("APP_")
+// would resolve the named arg "PORT" from the environment variable "APP_PORT"
+func EnvArgs(prefix string) ArgResolver {
+	return &envArgs{
+		prefix: prefix,
+	}
+}
+
+type envArgs struct {
+	prefix string
+}
+
+func (e *envArgs) Resolve(name string) (interface{}, bool) {
+	if v, ok := os.LookupEnv(e.prefix + name); ok {
+		return v, true
+	}
+	return nil, false
+}
+
+// ChainArgs returns an ArgResolver that resolves named args from each of the specified
+// resolvers in turn - the first resolver that has the named arg present is used
+func ChainArgs(resolvers ...ArgResolver) ArgResolver {
+	return chainArgs(resolvers)
+}
+
+type chainArgs []ArgResolver
+
+func (c chainArgs) Resolve(name string) (interface{}, bool) {
+	for _, r := range c {
+		if r != nil {
+			if v, ok := r.Resolve(name); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,79 @@
+package jsont
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+)
+
+func TestNamedTemplate_Render(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"foo":?foo,"bar":?bar}`)
+	require.NoError(t, err)
+
+	data, err := jt.Render(MapArgs{"foo": "aaa", "bar": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"aaa","bar":1}`, string(data[:]))
+	str, err := jt.RenderString(MapArgs{"foo": "aaa", "bar": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"aaa","bar":1}`, str)
+
+	_, err = jt.Render(MapArgs{"foo": "aaa"})
+	require.Error(t, err)
+	require.Equal(t, "expected named arg 'bar'", err.Error())
+	_, err = jt.RenderString(nil)
+	require.Error(t, err)
+	require.Equal(t, "expected named arg 'foo'", err.Error())
+
+	jt = jt.DefaultArgValue("bar", 2)
+	str, err = jt.RenderString(MapArgs{"foo": "aaa"})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"aaa","bar":2}`, str)
+
+	jt = jt.Options(OptionNonStrict)
+	data, err = jt.Render(nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":null,"bar":2}`, string(data[:]))
+}
+
+func TestURLValuesArgs(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"foo":?foo,"bar":?bar}`)
+	require.NoError(t, err)
+
+	str, err := jt.RenderString(URLValuesArgs(url.Values{"foo": {"aaa"}, "bar": {"b1", "b2"}}))
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"aaa","bar":["b1","b2"]}`, str)
+
+	_, err = jt.RenderString(URLValuesArgs(url.Values{"foo": {"aaa"}, "bar": {}}))
+	require.Error(t, err)
+	require.Equal(t, "expected named arg 'bar'", err.Error())
+}
+
+func TestEnvArgs(t *testing.T) {
+	t.Setenv("JSONT_TEST_FOO", "aaa")
+	jt, err := NewNamedTemplate(`{"foo":?FOO,"bar":?BAR}`)
+	require.NoError(t, err)
+
+	_, err = jt.RenderString(EnvArgs("JSONT_TEST_"))
+	require.Error(t, err)
+	require.Equal(t, "expected named arg 'BAR'", err.Error())
+
+	t.Setenv("JSONT_TEST_BAR", "")
+	str, err := jt.RenderString(EnvArgs("JSONT_TEST_"))
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"aaa","bar":""}`, str)
+}
+
+func TestChainArgs(t *testing.T) {
+	t.Setenv("JSONT_TEST_BAR", "from env")
+	jt, err := NewNamedTemplate(`{"foo":?foo,"bar":?JSONT_TEST_BAR,"baz":?baz,"qux":?qux.name}`)
+	require.NoError(t, err)
+
+	resolver := ChainArgs(nil, MapArgs{"foo": "from map", "JSONT_TEST_BAR": nil}, EnvArgs(""), URLValuesArgs(url.Values{"baz": {"from url"}}), MapArgs{"qux": map[string]interface{}{"name": "from path"}})
+	str, err := jt.RenderString(resolver)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"from map","bar":null,"baz":"from url","qux":"from path"}`, str)
+
+	_, err = jt.RenderString(ChainArgs())
+	require.Error(t, err)
+	require.Equal(t, "expected named arg 'foo'", err.Error())
+}
@@ -0,0 +1,164 @@
+package jsont
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// filterSchemaTypes is the JSON Schema types output by built-in filters
+var filterSchemaTypes = map[string]string{
+	"upper":   "string",
+	"lower":   "string",
+	"trim":    "string",
+	"trunc":   "string",
+	"string":  "string",
+	"rfc3339": "string",
+	"date":    "string",
+	"unix":    "integer",
+	"cents":   "number",
+}
+
+// envArgSchemaTypes is the JSON Schema types of environment variable args (by coercion type)
+var envArgSchemaTypes = map[EnvArgType]string{
+	EnvString: "string",
+	EnvNumber: "number",
+	EnvInt:    "integer",
+	EnvBool:   "boolean",
+}
+
+// schema generates a JSON Schema (draft 2020-12) describing the output of the template - using the argSchema func
+// to describe the value of each arg marker
+func (t tokens) schema(argSchema func(tkn jsonTemplateToken) map[string]interface{}) (map[string]interface{}, error) {
+	root := newStructureScanner(t).scan()
+	if root == nil {
+		return nil, errors.New("cannot generate schema - template structure is not well-formed JSON")
+	}
+	result := t.nodeSchema(root, argSchema)
+	result["$schema"] = schemaDialect
+	return result, nil
+}
+
+func (t tokens) nodeSchema(node *structureNode, argSchema func(tkn jsonTemplateToken) map[string]interface{}) map[string]interface{} {
+	switch node.kind {
+	case structureArg:
+		return argSchema(t[node.token])
+	case structureObject:
+		result := map[string]interface{}{"type": "object"}
+		if len(node.members) > 0 {
+			properties := make(map[string]interface{}, len(node.members))
+			required := make([]string, 0, len(node.members))
+			for _, m := range node.members {
+				if _, exists := properties[m.name]; !exists {
+					required = append(required, m.name)
+				}
+				properties[m.name] = t.nodeSchema(m.value, argSchema)
+			}
+			result["properties"] = properties
+			result["required"] = required
+		}
+		if !node.open {
+			result["additionalProperties"] = false
+		}
+		return result
+	case structureArray:
+		result := map[string]interface{}{"type": "array"}
+		if len(node.items) > 0 {
+			items := make([]interface{}, 0, len(node.items))
+			for _, item := range node.items {
+				items = append(items, t.nodeSchema(item, argSchema))
+			}
+			result["prefixItems"] = items
+			result["minItems"] = len(items)
+		}
+		if !node.open {
+			result["maxItems"] = len(node.items)
+		}
+		return result
+	case structureString:
+		if node.hasArgs {
+			return map[string]interface{}{"type": "string"}
+		}
+	}
+	return literalSchema(node.literal)
+}
+
+func literalSchema(literal []byte) map[string]interface{} {
+	v, err := unmarshalSchemaValue(literal)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	result := map[string]interface{}{"const": v}
+	switch vt := v.(type) {
+	case nil:
+		result["type"] = "null"
+	case bool:
+		result["type"] = "boolean"
+	case string:
+		result["type"] = "string"
+	case json.Number:
+		if strings.ContainsAny(vt.String(), ".eE") {
+			result["type"] = "number"
+		} else {
+			result["type"] = "integer"
+		}
+	}
+	return result
+}
+
+// unmarshalSchemaValue unmarshals a single JSON value (using json.Number for numbers) - data following the
+// value (other than whitespace) is an error
+func unmarshalSchemaValue(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	offset := decoder.InputOffset()
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after top-level value at offset %d", offset)
+	}
+	return v, nil
+}
+
+func positionalArgSchema(tkn jsonTemplateToken) map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// namedArgSchema describes the value of a named arg marker - typed according to the last filter (for built-in
+// filters) or the environment variable type (for env args) and with any default value
+func (t *jsonNamedTemplate) namedArgSchema(tkn jsonTemplateToken) map[string]interface{} {
+	result := map[string]interface{}{}
+	if l := len(tkn.filters); l > 0 {
+		last := tkn.filters[l-1].name
+		if _, custom := t.filters[last]; !custom {
+			if typ, ok := filterSchemaTypes[last]; ok {
+				result["type"] = []string{typ, "null"}
+			}
+		}
+	} else if t.envArgs && len(tkn.fallbacks) == 0 && isEnvArgName(tkn.argName) {
+		result["type"] = []string{envArgSchemaTypes[t.envArgTypes[tkn.argName[len(envArgPrefix):]]], "null"}
+	}
+	for _, name := range tkn.chainNames() {
+		if dv, ok := t.defaultArgValues[name]; ok {
+			if _, dynamic := dv.(*dynamicDefault); dynamic {
+				// dynamic defaults have no fixed value...
+				break
+			}
+			if data, err := t.namedArgData(context.Background(), tkn, rawDefault(dv)); err == nil {
+				if v, err := unmarshalSchemaValue(data); err == nil {
+					result["default"] = v
+				}
+			}
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package jsont
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNamedTemplateSchema(t *testing.T) {
+	jt, err := NewNamedTemplate(`{
+  "type": "user",
+  "version": 2,
+  "ratio": 1.5,
+  "active": true,
+  "deleted": null,
+  "name": ?name|upper,
+  "greeting": "hello ?name",
+  "tags": [?tag, "fixed"],
+  "address": {"city": ?city?:town},
+  "amount": ?amount|cents
+}`, OptionDefaultArgValue("town", "hobbiton"))
+	require.NoError(t, err)
+	schema, err := jt.Schema()
+	require.NoError(t, err)
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	const expect = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["type","version","ratio","active","deleted","name","greeting","tags","address","amount"],
+  "properties": {
+    "type": {"type": "string", "const": "user"},
+    "version": {"type": "integer", "const": 2},
+    "ratio": {"type": "number", "const": 1.5},
+    "active": {"type": "boolean", "const": true},
+    "deleted": {"type": "null", "const": null},
+    "name": {"type": ["string", "null"]},
+    "greeting": {"type": "string"},
+    "tags": {"type": "array", "minItems": 2, "maxItems": 2, "prefixItems": [{}, {"type": "string", "const": "fixed"}]},
+    "address": {"type": "object", "additionalProperties": false, "required": ["city"], "properties": {"city": {"default": "hobbiton"}}},
+    "amount": {"type": ["number", "null"]}
+  }
+}`
+	require.JSONEq(t, expect, string(data))
+}
+
+func TestNamedTemplateSchemaDefaultsAndEnv(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"name":?name|upper,"port":?env.PORT,"host":?env.HOST,?extra}`,
+		OptionDefaultArgValue("name", "bilbo"),
+		OptionEnvArgTypes(map[string]EnvArgType{"PORT": EnvInt}),
+		OptionFilters(map[string]FilterFunc{"upper": func(value interface{}, args ...string) (interface{}, error) {
+			return 1, nil
+		}}))
+	require.NoError(t, err)
+	schema, err := jt.Schema()
+	require.NoError(t, err)
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["name","port","host"],
+  "properties": {
+    "name": {"default": 1},
+    "port": {"type": ["integer", "null"]},
+    "host": {"type": ["string", "null"]}
+  }
+}`, string(data))
+}
+
+func TestTemplateSchema(t *testing.T) {
+	jt, err := NewTemplate(`[?, [], [1?], "a\"b"]`)
+	require.NoError(t, err)
+	schema, err := jt.Schema()
+	require.NoError(t, err)
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "array",
+  "minItems": 4,
+  "maxItems": 4,
+  "prefixItems": [
+    {},
+    {"type": "array", "maxItems": 0},
+    {"type": "array", "minItems": 1, "prefixItems": [{"type": "integer", "const": 1}]},
+    {"type": "string", "const": "a\"b"}
+  ]
+}`, string(data))
+
+	jt, err = NewTemplate(`?`)
+	require.NoError(t, err)
+	schema, err = jt.Schema()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"$schema": schemaDialect}, schema)
+
+	jt, err = NewTemplate(`{"a":? "b"}`)
+	require.NoError(t, err)
+	_, err = jt.Schema()
+	require.Error(t, err)
+	require.Equal(t, "cannot generate schema - template structure is not well-formed JSON", err.Error())
+}
+
+func TestMixedTemplateSchema(t *testing.T) {
+	jt, err := NewMixedTemplate(`{"name":?name|lower,"value":?}`, OptionDefaultArgValue("name", "BILBO"))
+	require.NoError(t, err)
+	schema, err := jt.Schema()
+	require.NoError(t, err)
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["name","value"],
+  "properties": {
+    "name": {"type": ["string", "null"], "default": "bilbo"},
+    "value": {}
+  }
+}`, string(data))
+}
@@ -0,0 +1,536 @@
+package jsont
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// jsonSchema is a compiled JSON Schema (see OptionSchema) used to validate rendered output
+//
+// The validator supports the commonly used draft 2020-12 keywords - type, const, enum, the numeric, string,
+// array and object constraints, the applicators (allOf, anyOf, oneOf, not, if/then/else) and local '$ref's
+// (e.g. '#/$defs/name') - schemas using other validation keywords (e.g. format) are rejected when compiled
+// (annotation keywords, e.g. title and description, are ignored)
+type jsonSchema struct {
+	root     interface{}
+	patterns sync.Map
+}
+
+// unsupportedSchemaKeywords is the keywords that the validator does not support (and would otherwise silently ignore)
+var unsupportedSchemaKeywords = []string{
+	"format", "propertyNames", "contains", "minContains", "maxContains", "unevaluatedProperties", "unevaluatedItems",
+	"dependentRequired", "dependentSchemas", "dependencies", "$anchor", "$dynamicRef", "$dynamicAnchor", "$recursiveRef",
+	"$recursiveAnchor", "contentSchema",
+}
+
+func compileSchema(data []byte) (*jsonSchema, error) {
+	root, err := unmarshalSchemaValue(data)
+	if err != nil {
+		return nil, err
+	}
+	switch root.(type) {
+	case bool, map[string]interface{}:
+		result := &jsonSchema{root: root}
+		if err = result.checkSupported(root, "#"); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	return nil, errors.New("schema must be an object or boolean")
+}
+
+// checkSupported checks that a schema (and its sub-schemas) only uses supported keywords, that all
+// '$ref's are local and resolvable and that all patterns (pattern and patternProperties) compile
+func (s *jsonSchema) checkSupported(schema interface{}, location string) error {
+	st, ok := schema.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, keyword := range unsupportedSchemaKeywords {
+		if _, ok := st[keyword]; ok {
+			return fmt.Errorf("unsupported keyword '%s' at '%s'", keyword, location)
+		}
+	}
+	if ref, ok := st["$ref"].(string); ok {
+		if _, ok := s.resolveRef(ref); !ok {
+			return fmt.Errorf("unsupported (non-local) or unresolvable '$ref' '%s' at '%s'", ref, location)
+		}
+	}
+	if pattern, ok := st["pattern"].(string); ok {
+		if _, err := s.pattern(pattern); err != nil {
+			return fmt.Errorf("invalid pattern '%s' at '%s': %s", pattern, location, err.Error())
+		}
+	}
+	for _, keyword := range []string{"items", "additionalProperties", "not", "if", "then", "else"} {
+		if sub, ok := st[keyword]; ok {
+			if err := s.checkSupported(sub, location+"/"+keyword); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"prefixItems", "allOf", "anyOf", "oneOf"} {
+		subs, _ := st[keyword].([]interface{})
+		for i, sub := range subs {
+			if err := s.checkSupported(sub, location+"/"+keyword+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"properties", "patternProperties", "$defs", "definitions"} {
+		subs, _ := st[keyword].(map[string]interface{})
+		names := make([]string, 0, len(subs))
+		for name := range subs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if keyword == "patternProperties" {
+				if _, err := s.pattern(name); err != nil {
+					return fmt.Errorf("invalid pattern '%s' at '%s/patternProperties': %s", name, location, err.Error())
+				}
+			}
+			if err := s.checkSupported(subs[name], location+"/"+keyword+"/"+escapePointerToken(name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validate validates the rendered output against the schema (a nil schema always validates)
+func (s *jsonSchema) validate(data []byte) error {
+	if s == nil {
+		return nil
+	}
+	v, err := unmarshalSchemaValue(data)
+	if err != nil {
+		return newSchemaError("", "", "output is not valid JSON: %s", err.Error())
+	}
+	sv := &schemaValidation{jsonSchema: s, activeRefs: map[schemaRefKey]bool{}}
+	return sv.validateValue(s.root, v, "")
+}
+
+// schemaValidation is the state of validating a single output against the schema
+type schemaValidation struct {
+	*jsonSchema
+	// activeRefs is the '$ref's currently being evaluated (for each instance location) - used to detect
+	// circular references that would otherwise never terminate (e.g. '{"$ref":"#"}')
+	activeRefs map[schemaRefKey]bool
+}
+
+type schemaRefKey struct {
+	pointer string
+	ref     string
+}
+
+func (s *schemaValidation) validateValue(schema interface{}, v interface{}, pointer string) error {
+	switch st := schema.(type) {
+	case bool:
+		if !st {
+			return newSchemaError(pointer, "false", "value not allowed")
+		}
+		return nil
+	case map[string]interface{}:
+		validators := []func(s *schemaValidation, schema map[string]interface{}, v interface{}, pointer string) error{
+			validateSchemaRef,
+			validateSchemaType,
+			validateSchemaConst,
+			validateSchemaNumber,
+			validateSchemaString,
+			validateSchemaArray,
+			validateSchemaObject,
+			validateSchemaApplicators,
+		}
+		for _, fn := range validators {
+			if err := fn(s, st, v, pointer); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateSchemaRef(s *schemaValidation, schema map[string]interface{}, v interface{}, pointer string) error {
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return nil
+	}
+	target, ok := s.resolveRef(ref)
+	if !ok {
+		return newSchemaError(pointer, "$ref", "cannot resolve schema reference '%s'", ref)
+	}
+	key := schemaRefKey{pointer: pointer, ref: ref}
+	if s.activeRefs[key] {
+		return newSchemaError(pointer, "$ref", "circular schema reference '%s'", ref)
+	}
+	s.activeRefs[key] = true
+	defer delete(s.activeRefs, key)
+	return s.validateValue(target, v, pointer)
+}
+
+func (s *jsonSchema) resolveRef(ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	current := s.root
+	if ref == "#" {
+		return current, true
+	} else if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch ct := current.(type) {
+		case map[string]interface{}:
+			next, ok := ct[token]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(ct) {
+				return nil, false
+			}
+			current = ct[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func validateSchemaType(s *schemaValidation, schema map[string]interface{}, v interface{}, pointer string) error {
+	var types []string
+	switch tt := schema["type"].(type) {
+	case string:
+		types = []string{tt}
+	case []interface{}:
+		for _, t := range tt {
+			if str, ok := t.(string); ok {
+				types = append(types, str)
+			}
+		}
+	default:
+		return nil
+	}
+	actual := schemaTypeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return nil
+		}
+	}
+	return newSchemaError(pointer, "type", "expected type %s but got %s", strings.Join(types, " or "), actual)
+}
+
+func schemaTypeOf(v interface{}) string {
+	switch vt := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if r, ok := new(big.Rat).SetString(vt.String()); ok && r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+func validateSchemaConst(s *schemaValidation, schema map[string]interface{}, v interface{}, pointer string) error {
+	if c, ok := schema["const"]; ok && !schemaValuesEqual(c, v) {
+		return newSchemaError(pointer, "const", "value does not match const %s", schemaValueString(c))
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		for _, e := range enum {
+			if schemaValuesEqual(e, v) {
+				return nil
+			}
+		}
+		return newSchemaError(pointer, "enum", "value is not one of the enum values")
+	}
+	return nil
+}
+
+func schemaValuesEqual(a, b interface{}) bool {
+	switch at := a.(type) {
+	case json.Number:
+		bt, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		ar, aok := new(big.Rat).SetString(at.String())
+		br, bok := new(big.Rat).SetString(bt.String())
+		return aok && bok && ar.Cmp(br) == 0
+	case []interface{}:
+		bt, ok := b.([]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for i := range at {
+			if !schemaValuesEqual(at[i], bt[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bt, ok := b.(map[string]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for k, av := range at {
+			if bv, ok := bt[k]; !ok || !schemaValuesEqual(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func schemaValueString(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func validateSchemaNumber(s *schemaValidation, schema map[string]interface{}, v interface{}, pointer string) error {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil
+	}
+	value, ok := new(big.Rat).SetString(n.String())
+	if !ok {
+		return nil
+	}
+	checks := []struct {
+		keyword string
+		fails   func(cmp int) bool
+		message string
+	}{
+		{"minimum", func(cmp int) bool { return cmp < 0 }, "value must be >= %s"},
+		{"maximum", func(cmp int) bool { return cmp > 0 }, "value must be <= %s"},
+		{"exclusiveMinimum", func(cmp int) bool { return cmp <= 0 }, "value must be > %s"},
+		{"exclusiveMaximum", func(cmp int) bool { return cmp >= 0 }, "value must be < %s"},
+	}
+	for _, check := range checks {
+		if limit, ok := schemaRat(schema[check.keyword]); ok && check.fails(value.Cmp(limit)) {
+			return newSchemaError(pointer, check.keyword, check.message, schema[check.keyword])
+		}
+	}
+	if m, ok := schemaRat(schema["multipleOf"]); ok && m.Sign() > 0 {
+		if !new(big.Rat).Quo(value, m).IsInt() {
+			return newSchemaError(pointer, "multipleOf", "value must be a multiple of %s", schema["multipleOf"])
+		}
+	}
+	return nil
+}
+
+func schemaRat(v interface{}) (*big.Rat, bool) {
+	if n, ok := v.(json.Number); ok {
+		return new(big.Rat).SetString(n.String())
+	}
+	return nil, false
+}
+
+func schemaInt(v interface{}) (int, bool) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := strconv.Atoi(n.String()); err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func validateSchemaString(s *schemaValidation, schema map[string]interface{}, v interface{}, pointer string) error {
+	str, ok := v.(string)
+	if !ok {
+		return nil
+	}
+	l := utf8.RuneCountInString(str)
+	if min, ok := schemaInt(schema["minLength"]); ok && l < min {
+		return newSchemaError(pointer, "minLength", "string length must be >= %d", min)
+	}
+	if max, ok := schemaInt(schema["maxLength"]); ok && l > max {
+		return newSchemaError(pointer, "maxLength", "string length must be <= %d", max)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		rx, err := s.pattern(pattern)
+		if err != nil {
+			return newSchemaError(pointer, "pattern", "invalid pattern '%s'", pattern)
+		} else if !rx.MatchString(str) {
+			return newSchemaError(pointer, "pattern", "string does not match pattern '%s'", pattern)
+		}
+	}
+	return nil
+}
+
+func (s *jsonSchema) pattern(pattern string) (*regexp.Regexp, error) {
+	if rx, ok := s.patterns.Load(pattern); ok {
+		return rx.(*regexp.Regexp), nil
+	}
+	rx, err := regexp.Compile(pattern)
+	if err == nil {
+		s.patterns.Store(pattern, rx)
+	}
+	return rx, err
+}
+
+func validateSchemaArray(s *schemaValidation, schema map[string]interface{}, v interface{}, pointer string) error {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	if min, ok := schemaInt(schema["minItems"]); ok && len(arr) < min {
+		return newSchemaError(pointer, "minItems", "array must have at least %d items", min)
+	}
+	if max, ok := schemaInt(schema["maxItems"]); ok && len(arr) > max {
+		return newSchemaError(pointer, "maxItems", "array must have at most %d items", max)
+	}
+	prefixItems, _ := schema["prefixItems"].([]interface{})
+	for i, item := range arr {
+		itemSchema, ok := schema["items"]
+		if i < len(prefixItems) {
+			itemSchema, ok = prefixItems[i], true
+		}
+		if ok {
+			if err := s.validateValue(itemSchema, item, pointer+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	if unique, ok := schema["uniqueItems"].(bool); ok && unique {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if schemaValuesEqual(arr[i], arr[j]) {
+					return newSchemaError(pointer, "uniqueItems", "array items must be unique (items %d and %d are equal)", i, j)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateSchemaObject(s *schemaValidation, schema map[string]interface{}, v interface{}, pointer string) error {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if min, ok := schemaInt(schema["minProperties"]); ok && len(obj) < min {
+		return newSchemaError(pointer, "minProperties", "object must have at least %d properties", min)
+	}
+	if max, ok := schemaInt(schema["maxProperties"]); ok && len(obj) > max {
+		return newSchemaError(pointer, "maxProperties", "object must have at most %d properties", max)
+	}
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, exists := obj[name]; !exists {
+					return newSchemaError(pointer, "required", "missing required property '%s'", name)
+				}
+			}
+		}
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	patternProperties, _ := schema["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+	// validate in sorted name order - so that the error reported is deterministic...
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := obj[name]
+		memberPointer := pointer + "/" + escapePointerToken(name)
+		matched := false
+		if ps, ok := properties[name]; ok {
+			matched = true
+			if err := s.validateValue(ps, value, memberPointer); err != nil {
+				return err
+			}
+		}
+		for pattern, ps := range patternProperties {
+			if rx, err := s.pattern(pattern); err == nil && rx.MatchString(name) {
+				matched = true
+				if err := s.validateValue(ps, value, memberPointer); err != nil {
+					return err
+				}
+			}
+		}
+		if !matched && hasAdditional {
+			if b, ok := additional.(bool); ok && !b {
+				return newSchemaError(memberPointer, "additionalProperties", "property '%s' is not allowed", name)
+			} else if err := s.validateValue(additional, value, memberPointer); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateSchemaApplicators(s *schemaValidation, schema map[string]interface{}, v interface{}, pointer string) error {
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			if err := s.validateValue(sub, v, pointer); err != nil {
+				return err
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if s.validateValue(sub, v, pointer) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return newSchemaError(pointer, "anyOf", "value does not match any of the schemas")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		count := 0
+		for _, sub := range oneOf {
+			if s.validateValue(sub, v, pointer) == nil {
+				count++
+			}
+		}
+		if count != 1 {
+			return newSchemaError(pointer, "oneOf", "value must match exactly one of the schemas (matched %d)", count)
+		}
+	}
+	if not, ok := schema["not"]; ok && s.validateValue(not, v, pointer) == nil {
+		return newSchemaError(pointer, "not", "value must not match the schema")
+	}
+	if ifSchema, ok := schema["if"]; ok {
+		if s.validateValue(ifSchema, v, pointer) == nil {
+			if then, ok := schema["then"]; ok {
+				return s.validateValue(then, v, pointer)
+			}
+		} else if elseSchema, ok := schema["else"]; ok {
+			return s.validateValue(elseSchema, v, pointer)
+		}
+	}
+	return nil
+}
+
+func newSchemaError(pointer string, keyword string, format string, a ...any) error {
+	return &SchemaError{
+		Pointer: pointer,
+		Keyword: keyword,
+		message: fmt.Sprintf("output does not match schema at '#%s': %s", pointer, fmt.Sprintf(format, a...)),
+	}
+}
@@ -0,0 +1,178 @@
+package jsont
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const testUserSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["name", "age", "tags"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "age": {"$ref": "#/$defs/age"},
+    "tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true}
+  },
+  "$defs": {
+    "age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150}
+  }
+}`
+
+func TestNamedTemplateOptionSchema(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"name":?name,"age":?age,"tags":?tags}`, OptionSchema([]byte(testUserSchema)))
+	require.NoError(t, err)
+
+	str, err := jt.String(map[string]interface{}{"name": "bilbo", "age": 111, "tags": []string{"hobbit"}})
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"bilbo","age":111,"tags":["hobbit"]}`, str)
+
+	_, err = jt.String(map[string]interface{}{"name": "bilbo", "age": 111, "tags": []interface{}{"hobbit", 1}})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrSchemaValidation))
+	var se *SchemaError
+	require.True(t, errors.As(err, &se))
+	require.Equal(t, "/tags/1", se.Pointer)
+	require.Equal(t, "type", se.Keyword)
+	require.Equal(t, "output does not match schema at '#/tags/1': expected type string but got integer", err.Error())
+
+	data, err := jt.Data(map[string]interface{}{"name": "bilbo", "age": 151, "tags": []string{}})
+	require.Error(t, err)
+	require.Nil(t, data)
+	require.Equal(t, "output does not match schema at '#/age': value must be < 150", err.Error())
+
+	jt2, err := jt.NewWith(map[string]interface{}{"name": ""})
+	require.NoError(t, err)
+	_, err = jt2.String(map[string]interface{}{"age": 1, "tags": nil})
+	require.Error(t, err)
+	require.Equal(t, "output does not match schema at '#/name': string length must be >= 1", err.Error())
+}
+
+func TestTemplateOptionSchema(t *testing.T) {
+	jt, err := NewTemplate(`[?,?]`, OptionChecked, OptionSchema([]byte(`{"type":"array","prefixItems":[{"enum":["a","b"]},{"const":{"x":1.0}}],"items":false}`)))
+	require.NoError(t, err)
+	str, err := jt.String("a", map[string]interface{}{"x": 1})
+	require.NoError(t, err)
+	require.Equal(t, `["a",{"x":1}]`, str)
+
+	str, err = jt.String("c", map[string]interface{}{"x": 1})
+	require.Error(t, err)
+	require.Equal(t, "null", str)
+	require.Equal(t, "output does not match schema at '#/0': value is not one of the enum values", err.Error())
+
+	_, err = jt.Data("b", map[string]interface{}{"x": 2})
+	require.Error(t, err)
+	require.Equal(t, `output does not match schema at '#/1': value does not match const {"x":1.0}`, err.Error())
+}
+
+func TestMixedTemplateOptionSchema(t *testing.T) {
+	jt, err := NewMixedTemplate(`{"name":?name,"extra":?}`, OptionSchema([]byte(`{"properties":{"extra":{"type":"boolean"}}}`)))
+	require.NoError(t, err)
+	_, err = jt.String(map[string]interface{}{"name": "x"}, true)
+	require.NoError(t, err)
+	_, err = jt.Data(map[string]interface{}{"name": "x"}, "true")
+	require.Error(t, err)
+	require.Equal(t, "output does not match schema at '#/extra': expected type boolean but got string", err.Error())
+}
+
+func TestOptionSchemaInvalid(t *testing.T) {
+	_, err := NewTemplate(`?`, OptionSchema([]byte(`{`)))
+	require.Error(t, err)
+	require.Equal(t, "option Schema - invalid schema: unexpected EOF", err.Error())
+
+	_, err = NewTemplate(`?`, OptionSchema([]byte(`[]`)))
+	require.Error(t, err)
+	require.Equal(t, "option Schema - invalid schema: schema must be an object or boolean", err.Error())
+
+	_, err = NewTemplate(`?`, OptionSchema([]byte(`{} {}`)))
+	require.Error(t, err)
+	require.Equal(t, "option Schema - invalid schema: invalid data after top-level value at offset 2", err.Error())
+}
+
+func TestOptionSchemaUnsupported(t *testing.T) {
+	testCases := []struct {
+		schema string
+		expect string
+	}{
+		{`{"format":"email"}`, "unsupported keyword 'format' at '#'"},
+		{`{"properties":{"a/b":{"items":{"contains":{}}}}}`, "unsupported keyword 'contains' at '#/properties/a~1b/items'"},
+		{`{"allOf":[{},{"propertyNames":{}}]}`, "unsupported keyword 'propertyNames' at '#/allOf/1'"},
+		{`{"not":{"unevaluatedProperties":false}}`, "unsupported keyword 'unevaluatedProperties' at '#/not'"},
+		{`{"$defs":{"a":{"dependentRequired":{}}}}`, "unsupported keyword 'dependentRequired' at '#/$defs/a'"},
+		{`{"$anchor":"a"}`, "unsupported keyword '$anchor' at '#'"},
+		{`{"items":{"$ref":"https://example.com/schema"}}`, "unsupported (non-local) or unresolvable '$ref' 'https://example.com/schema' at '#/items'"},
+		{`{"$ref":"#/$defs/missing"}`, "unsupported (non-local) or unresolvable '$ref' '#/$defs/missing' at '#'"},
+		{`{"$ref":"#a"}`, "unsupported (non-local) or unresolvable '$ref' '#a' at '#'"},
+		{`{"properties":{"a":{"pattern":"("}}}`, "invalid pattern '(' at '#/properties/a': error parsing regexp: missing closing ): `(`"},
+		{`{"patternProperties":{"(":{}}}`, "invalid pattern '(' at '#/patternProperties': error parsing regexp: missing closing ): `(`"},
+	}
+	for i, tc := range testCases {
+		_, err := NewNamedTemplate(`{"a":?a}`, OptionSchema([]byte(tc.schema)))
+		require.Error(t, err, "test case %d", i)
+		require.Equal(t, "option Schema - invalid schema: "+tc.expect, err.Error(), "test case %d", i)
+	}
+
+	// keywords within values (e.g. const) are not schema keywords...
+	_, err := NewNamedTemplate(`{"a":?a}`, OptionSchema([]byte(`{"const":{"format":"x"},"properties":{"format":{"enum":[{"$ref":"x"}]}}}`)))
+	require.NoError(t, err)
+}
+
+func TestSchemaValidateKeywords(t *testing.T) {
+	testCases := []struct {
+		schema string
+		data   string
+		expect string
+	}{
+		{`true`, `1`, ""},
+		{`false`, `1`, "output does not match schema at '#': value not allowed"},
+		{`{"type":["string","null"]}`, `null`, ""},
+		{`{"type":"integer"}`, `1.0`, ""},
+		{`{"type":"integer"}`, `1.5`, "output does not match schema at '#': expected type integer but got number"},
+		{`{"type":"number"}`, `1`, ""},
+		{`{"minimum":1,"maximum":2}`, `0`, "output does not match schema at '#': value must be >= 1"},
+		{`{"minimum":1,"maximum":2}`, `3`, "output does not match schema at '#': value must be <= 2"},
+		{`{"exclusiveMinimum":1}`, `1`, "output does not match schema at '#': value must be > 1"},
+		{`{"multipleOf":0.1}`, `0.3`, ""},
+		{`{"multipleOf":2}`, `3`, "output does not match schema at '#': value must be a multiple of 2"},
+		{`{"maxLength":2}`, `"äöü"`, "output does not match schema at '#': string length must be <= 2"},
+		{`{"pattern":"^a+$"}`, `"aaa"`, ""},
+		{`{"pattern":"^a+$"}`, `"ab"`, "output does not match schema at '#': string does not match pattern '^a+$'"},
+		{`{"minItems":2}`, `[1]`, "output does not match schema at '#': array must have at least 2 items"},
+		{`{"maxItems":0}`, `[1]`, "output does not match schema at '#': array must have at most 0 items"},
+		{`{"uniqueItems":true}`, `[1,2,1.0]`, "output does not match schema at '#': array items must be unique (items 0 and 2 are equal)"},
+		{`{"required":["a"]}`, `{}`, "output does not match schema at '#': missing required property 'a'"},
+		{`{"minProperties":1}`, `{}`, "output does not match schema at '#': object must have at least 1 properties"},
+		{`{"maxProperties":0}`, `{"a":1}`, "output does not match schema at '#': object must have at most 0 properties"},
+		{`{"patternProperties":{"^x-":{"type":"string"}},"additionalProperties":false}`, `{"x-a":"1"}`, ""},
+		{`{"patternProperties":{"^x-":{"type":"string"}},"additionalProperties":false}`, `{"x-a":1}`, "output does not match schema at '#/x-a': expected type string but got integer"},
+		{`{"patternProperties":{"^x-":{"type":"string"}},"additionalProperties":false}`, `{"a/b":1}`, "output does not match schema at '#/a~1b': property 'a/b' is not allowed"},
+		{`{"additionalProperties":{"type":"boolean"}}`, `{"a":1}`, "output does not match schema at '#/a': expected type boolean but got integer"},
+		{`{"allOf":[{"type":"integer"},{"minimum":5}]}`, `4`, "output does not match schema at '#': value must be >= 5"},
+		{`{"anyOf":[{"type":"string"},{"type":"boolean"}]}`, `4`, "output does not match schema at '#': value does not match any of the schemas"},
+		{`{"oneOf":[{"type":"integer"},{"type":"number"}]}`, `4`, "output does not match schema at '#': value must match exactly one of the schemas (matched 2)"},
+		{`{"not":{"type":"null"}}`, `null`, "output does not match schema at '#': value must not match the schema"},
+		{`{"if":{"type":"string"},"then":{"minLength":2},"else":{"type":"integer"}}`, `"a"`, "output does not match schema at '#': string length must be >= 2"},
+		{`{"if":{"type":"string"},"then":{"minLength":2},"else":{"type":"integer"}}`, `true`, "output does not match schema at '#': expected type integer but got boolean"},
+		{`{"$ref":"#"}`, `1`, "output does not match schema at '#': circular schema reference '#'"},
+		{`{"$defs":{"a":{"$ref":"#/$defs/b"},"b":{"anyOf":[{"$ref":"#/$defs/a"}]}},"$ref":"#/$defs/a"}`, `1`, "output does not match schema at '#': value does not match any of the schemas"},
+		{`{"items":{"$ref":"#"},"type":["array","integer"]}`, `[1,[2,["x"]]]`, "output does not match schema at '#/1/1/0': expected type array or integer but got string"},
+		{`{"prefixItems":[{"$ref":"#/prefixItems/1"},{"type":"string"}]}`, `[1]`, "output does not match schema at '#/0': expected type string but got integer"},
+		{`{"title":"annotations are ignored","description":"x"}`, `"x"`, ""},
+		{`{}`, `{`, "output does not match schema at '#': output is not valid JSON: unexpected EOF"},
+		{`{}`, `1 2`, "output does not match schema at '#': output is not valid JSON: invalid data after top-level value at offset 1"},
+		{`{}`, `{}]`, "output does not match schema at '#': output is not valid JSON: invalid data after top-level value at offset 2"},
+	}
+	for i, tc := range testCases {
+		schema, err := compileSchema([]byte(tc.schema))
+		require.NoError(t, err)
+		err = schema.validate([]byte(tc.data))
+		if tc.expect == "" {
+			require.NoError(t, err, "test case %d", i)
+		} else {
+			require.Error(t, err, "test case %d", i)
+			require.Equal(t, tc.expect, err.Error(), "test case %d", i)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package jsont
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ArgInfo describes an arg marker in a template (see Args methods on templates)
+type ArgInfo struct {
+	// Name is the arg name (empty for positional args)
+	Name string
+	// Fallbacks is any fallback arg names (for named args)
+	Fallbacks []string
+	// Filters is the names of any filters on the named arg
+	Filters []string
+	// Index is the (0 based) positional arg index (-1 for named args)
+	Index int
+	// Position is the position of the arg marker in the template
+	Position Position
+	// Pointer is the RFC 6901 JSON Pointer of where the arg value lands in the output - see Context
+	// for how this pointer relates to the arg marker
+	Pointer string
+	// Context describes the structural context of the arg marker
+	Context ArgContext
+}
+
+// ArgContext describes the structural context of an arg marker within the JSON template
+type ArgContext int
+
+const (
+	// ArgContextUnknown the structure of the template (at the arg marker) could not be determined
+	ArgContextUnknown ArgContext = iota
+	// ArgContextValue the arg marker is a JSON value - the Pointer is the location of the value
+	ArgContextValue
+	// ArgContextMembers the arg marker provides object members (e.g. using NameValues) - the Pointer is the location of the object
+	ArgContextMembers
+	// ArgContextKey the arg marker is an object member key - the Pointer is the location of the object
+	ArgContextKey
+	// ArgContextInString the arg marker is within a JSON string - the Pointer is the location of the string
+	ArgContextInString
+)
+
+func (c ArgContext) String() string {
+	switch c {
+	case ArgContextValue:
+		return "value"
+	case ArgContextMembers:
+		return "members"
+	case ArgContextKey:
+		return "key"
+	case ArgContextInString:
+		return "in-string"
+	}
+	return "unknown"
+}
+
+// argInfos builds the arg info for each arg marker token
+func (t tokens) argInfos() []ArgInfo {
+	s := newStructureScanner(t)
+	s.scan()
+	result := make([]ArgInfo, 0)
+	for i, tkn := range t {
+		if !tkn.fixed {
+			info := ArgInfo{
+				Name:      tkn.argName,
+				Fallbacks: copyStrings(tkn.fallbacks),
+				Index:     tkn.argIndex,
+				Position:  tkn.pos,
+			}
+			if tkn.argName != "" {
+				info.Index = -1
+				for _, f := range tkn.filters {
+					info.Filters = append(info.Filters, f.name)
+				}
+			}
+			if st, ok := s.args[i]; ok {
+				info.Pointer = st.pointer
+				info.Context = st.context
+			}
+			result = append(result, info)
+		}
+	}
+	return result
+}
+
+// copyArgInfos deep copies arg infos - so that callers cannot change the arg infos (or tokens) of a template
+func copyArgInfos(infos []ArgInfo) []ArgInfo {
+	result := make([]ArgInfo, len(infos))
+	for i, info := range infos {
+		info.Fallbacks = copyStrings(info.Fallbacks)
+		info.Filters = copyStrings(info.Filters)
+		result[i] = info
+	}
+	return result
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(s)), s...)
+}
+
+type structureKind int
+
+const (
+	structureLiteral structureKind = iota
+	structureString
+	structureObject
+	structureArray
+	structureArg
+)
+
+// structureNode is a node in the structure of a JSON template (as determined by a structureScanner)
+type structureNode struct {
+	kind structureKind
+	// members is the known members of an object
+	members []structureMember
+	// items is the (positionally known) items of an array
+	items []*structureNode
+	// open indicates an object or array has members/items (or keys) provided by arg markers
+	open bool
+	// literal is the raw JSON of a fixed string or literal
+	literal []byte
+	// hasArgs indicates a string contains arg markers
+	hasArgs bool
+	// token is the token index of an arg
+	token int
+}
+
+type structureMember struct {
+	name  string
+	value *structureNode
+}
+
+type argStructure struct {
+	pointer string
+	context ArgContext
+}
+
+// structureScanner performs a tolerant structural pass over the tokens of a template - determining the
+// JSON structure and the location of arg markers within that structure
+//
+// If the template is not well-formed JSON, scanning stops (and arg markers not yet reached have an unknown context)
+type structureScanner struct {
+	tkns   tokens
+	ti     int
+	bi     int
+	failed bool
+	args   map[int]argStructure
+}
+
+func newStructureScanner(tkns tokens) *structureScanner {
+	return &structureScanner{
+		tkns: tkns,
+		args: map[int]argStructure{},
+	}
+}
+
+// scan scans the template - returning the root node (or nil if the structure could not be determined)
+func (s *structureScanner) scan() *structureNode {
+	root := s.parseValue("", true)
+	if s.failed {
+		return nil
+	}
+	return root
+}
+
+// current returns the current byte (or whether the current position is an arg marker)
+func (s *structureScanner) current() (b byte, isArg bool, ok bool) {
+	for s.ti < len(s.tkns) {
+		tkn := s.tkns[s.ti]
+		if !tkn.fixed {
+			return 0, true, true
+		} else if s.bi < len(tkn.fixedValue) {
+			return tkn.fixedValue[s.bi], false, true
+		}
+		s.ti++
+		s.bi = 0
+	}
+	return 0, false, false
+}
+
+func (s *structureScanner) next() {
+	if s.ti < len(s.tkns) {
+		if s.tkns[s.ti].fixed {
+			s.bi++
+		} else {
+			s.ti++
+			s.bi = 0
+		}
+	}
+}
+
+func (s *structureScanner) skipWhitespace() {
+	for {
+		if b, isArg, ok := s.current(); ok && !isArg && (b == ' ' || b == '\t' || b == '\n' || b == '\r') {
+			s.next()
+		} else {
+			return
+		}
+	}
+}
+
+// isNext determines whether the next (non-whitespace) byte is the specified byte - consuming it if it is
+func (s *structureScanner) isNext(c byte) bool {
+	s.skipWhitespace()
+	if b, isArg, ok := s.current(); ok && !isArg && b == c {
+		s.next()
+		return true
+	}
+	return false
+}
+
+func (s *structureScanner) record(ti int, pointer string, context ArgContext) {
+	s.args[ti] = argStructure{
+		pointer: pointer,
+		context: context,
+	}
+}
+
+func (s *structureScanner) fail() *structureNode {
+	s.failed = true
+	return nil
+}
+
+func (s *structureScanner) parseValue(pointer string, known bool) *structureNode {
+	s.skipWhitespace()
+	b, isArg, ok := s.current()
+	if !ok {
+		return s.fail()
+	} else if isArg {
+		if known {
+			s.record(s.ti, pointer, ArgContextValue)
+		}
+		result := &structureNode{kind: structureArg, token: s.ti}
+		s.next()
+		return result
+	}
+	switch b {
+	case '{':
+		return s.parseObject(pointer, known)
+	case '[':
+		return s.parseArray(pointer, known)
+	case '"':
+		return s.parseString(pointer, known, ArgContextInString)
+	}
+	return s.parseLiteral()
+}
+
+func (s *structureScanner) parseObject(pointer string, known bool) *structureNode {
+	s.next()
+	result := &structureNode{kind: structureObject}
+	if s.isNext('}') {
+		return result
+	}
+	for !s.failed {
+		s.skipWhitespace()
+		b, isArg, ok := s.current()
+		if !ok {
+			return s.fail()
+		} else if isArg {
+			ti := s.ti
+			s.next()
+			result.open = true
+			if s.isNext(':') {
+				s.recordIf(known, ti, pointer, ArgContextKey)
+				s.parseValue("", false)
+			} else {
+				s.recordIf(known, ti, pointer, ArgContextMembers)
+			}
+		} else if b == '"' {
+			key := s.parseString(pointer, known, ArgContextKey)
+			if s.failed || !s.isNext(':') {
+				return s.fail()
+			}
+			var name string
+			nameOk := !key.hasArgs && json.Unmarshal(key.literal, &name) == nil
+			value := s.parseValue(pointer+"/"+escapePointerToken(name), known && nameOk)
+			if nameOk {
+				result.members = append(result.members, structureMember{name: name, value: value})
+			} else {
+				result.open = true
+			}
+		} else {
+			return s.fail()
+		}
+		if s.isNext('}') {
+			return result
+		} else if !s.isNext(',') {
+			// an arg marker may provide members without a preceding comma (e.g. `{"a":1?}`)
+			if _, isArg, ok = s.current(); !ok || !isArg {
+				return s.fail()
+			}
+		}
+	}
+	return nil
+}
+
+func (s *structureScanner) recordIf(known bool, ti int, pointer string, context ArgContext) {
+	if known {
+		s.record(ti, pointer, context)
+	}
+}
+
+func (s *structureScanner) parseArray(pointer string, known bool) *structureNode {
+	s.next()
+	result := &structureNode{kind: structureArray}
+	if s.isNext(']') {
+		return result
+	}
+	for !s.failed {
+		item := s.parseValue(pointer+"/"+strconv.Itoa(len(result.items)), known && !result.open)
+		if s.failed {
+			return nil
+		} else if !result.open {
+			// once items are provided by an arg marker, the position of subsequent items is not known
+			result.items = append(result.items, item)
+		}
+		if s.isNext(']') {
+			return result
+		} else if !s.isNext(',') {
+			// an arg marker may provide items without a preceding comma (e.g. `[1?]`)
+			if _, isArg, ok := s.current(); !ok || !isArg {
+				return s.fail()
+			}
+			result.open = true
+		}
+	}
+	return nil
+}
+
+func (s *structureScanner) parseString(pointer string, known bool, argContext ArgContext) *structureNode {
+	s.next()
+	result := &structureNode{kind: structureString}
+	literal := []byte{'"'}
+	for {
+		b, isArg, ok := s.current()
+		if !ok {
+			return s.fail()
+		} else if isArg {
+			s.recordIf(known, s.ti, pointer, argContext)
+			result.hasArgs = true
+			s.next()
+			continue
+		}
+		literal = append(literal, b)
+		s.next()
+		if b == '"' {
+			break
+		} else if b == '\\' {
+			if b, isArg, ok = s.current(); ok && !isArg {
+				literal = append(literal, b)
+				s.next()
+			}
+		}
+	}
+	if !result.hasArgs {
+		result.literal = literal
+	}
+	return result
+}
+
+func (s *structureScanner) parseLiteral() *structureNode {
+	literal := make([]byte, 0)
+	for {
+		b, isArg, ok := s.current()
+		if !ok || isArg || b == ',' || b == '}' || b == ']' || b == ':' || b == ' ' || b == '\t' || b == '\n' || b == '\r' {
+			break
+		}
+		literal = append(literal, b)
+		s.next()
+	}
+	if len(literal) == 0 || !json.Valid(literal) {
+		return s.fail()
+	}
+	return &structureNode{kind: structureLiteral, literal: literal}
+}
+
+// escapePointerToken escapes a JSON Pointer reference token (see RFC 6901)
+func escapePointerToken(token string) string {
+	if strings.ContainsAny(token, "~/") {
+		return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+	}
+	return token
+}
@@ -0,0 +1,143 @@
+package jsont
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNamedTemplateArgs(t *testing.T) {
+	jt, err := NewNamedTemplate(`{
+  "data": {
+    "items": [{"id": ?id, "name": ?name|upper}],
+    "a/b~c": ?slashed?:other
+  },
+  "msg": "hello ?who",
+  ?extra
+}`)
+	require.NoError(t, err)
+	args := jt.Args()
+	require.Equal(t, 5, len(args))
+
+	require.Equal(t, "id", args[0].Name)
+	require.Equal(t, -1, args[0].Index)
+	require.Equal(t, "/data/items/0/id", args[0].Pointer)
+	require.Equal(t, ArgContextValue, args[0].Context)
+	require.Equal(t, Position{Offset: 35, Line: 3, Column: 22}, args[0].Position)
+
+	require.Equal(t, "name", args[1].Name)
+	require.Equal(t, []string{"upper"}, args[1].Filters)
+	require.Equal(t, "/data/items/0/name", args[1].Pointer)
+
+	require.Equal(t, "slashed", args[2].Name)
+	require.Equal(t, []string{"other"}, args[2].Fallbacks)
+	require.Equal(t, "/data/a~1b~0c", args[2].Pointer)
+
+	require.Equal(t, "who", args[3].Name)
+	require.Equal(t, "/msg", args[3].Pointer)
+	require.Equal(t, ArgContextInString, args[3].Context)
+
+	require.Equal(t, "extra", args[4].Name)
+	require.Equal(t, "", args[4].Pointer)
+	require.Equal(t, ArgContextMembers, args[4].Context)
+	require.Equal(t, 7, args[4].Position.Line)
+	require.Equal(t, 3, args[4].Position.Column)
+
+	jt2, err := jt.NewWith(map[string]interface{}{"id": 1})
+	require.NoError(t, err)
+	args = jt2.Args()
+	require.Equal(t, 4, len(args))
+	require.Equal(t, args, (jt2.(*jsonNamedTemplate)).argInfos)
+}
+
+func TestArgsCannotChangeTemplate(t *testing.T) {
+	jt := MustCompileNamedTemplate(`{"a":?a?:b|upper}`)
+	args := jt.Args()
+	args[0].Fallbacks[0] = "zzz"
+	args[0].Filters[0] = "lower"
+	args = jt.Args()
+	require.Equal(t, []string{"b"}, args[0].Fallbacks)
+	require.Equal(t, []string{"upper"}, args[0].Filters)
+
+	_, err := jt.String(nil)
+	require.Error(t, err)
+	var mae *MissingArgError
+	require.ErrorAs(t, err, &mae)
+	mae.Fallbacks[0] = "zzz"
+	_, err = jt.String(nil)
+	require.Error(t, err)
+	require.Equal(t, "expected one of named args 'a', 'b'", err.Error())
+	str, err := jt.String(map[string]interface{}{"b": "bbb"})
+	require.NoError(t, err)
+	require.Equal(t, `{"a":"BBB"}`, str)
+}
+
+func TestTemplateArgs(t *testing.T) {
+	jt, err := NewTemplate(`[?1, {"a": [1, ?2]}, ?1, "x"?1]`)
+	require.NoError(t, err)
+	args := jt.Args()
+	require.Equal(t, 4, len(args))
+	require.Equal(t, ArgInfo{Index: 0, Position: Position{Offset: 1, Line: 1, Column: 2}, Pointer: "/0", Context: ArgContextValue}, args[0])
+	require.Equal(t, "/1/a/1", args[1].Pointer)
+	require.Equal(t, 1, args[1].Index)
+	require.Equal(t, "/2", args[2].Pointer)
+	require.Equal(t, 0, args[2].Index)
+	// arg directly following an item (without a comma) provides items...
+	require.Equal(t, ArgContextUnknown, args[3].Context)
+	// computed when parsed - and a copy is returned...
+	require.Equal(t, args, (jt.(*jsonTemplate)).argInfos)
+	args[0].Pointer = "changed"
+	require.Equal(t, "/0", jt.Args()[0].Pointer)
+
+	jt, err = NewTemplate(`{"a":?,"b":?}`)
+	require.NoError(t, err)
+	jt2, err := jt.NewWith("aaa")
+	require.NoError(t, err)
+	args = jt2.Args()
+	require.Equal(t, 1, len(args))
+	require.Equal(t, "/b", args[0].Pointer)
+	require.Equal(t, 0, args[0].Index)
+}
+
+func TestTemplateArgsKeysAndMembers(t *testing.T) {
+	jt, err := NewTemplate(`{"a":{?:1, "b":2 ?}}`)
+	require.NoError(t, err)
+	args := jt.Args()
+	require.Equal(t, 2, len(args))
+	require.Equal(t, "/a", args[0].Pointer)
+	require.Equal(t, ArgContextKey, args[0].Context)
+	require.Equal(t, "/a", args[1].Pointer)
+	require.Equal(t, ArgContextMembers, args[1].Context)
+}
+
+func TestTemplateArgsMalformed(t *testing.T) {
+	jt, err := NewTemplate(`{"a":?,"b" ?}`)
+	require.NoError(t, err)
+	args := jt.Args()
+	require.Equal(t, 2, len(args))
+	require.Equal(t, "/a", args[0].Pointer)
+	require.Equal(t, ArgContextValue, args[0].Context)
+	require.Equal(t, "", args[1].Pointer)
+	require.Equal(t, ArgContextUnknown, args[1].Context)
+}
+
+func TestMixedTemplateArgs(t *testing.T) {
+	jt, err := NewMixedTemplate(`{"name":?name,"values":[?1,?1]}`)
+	require.NoError(t, err)
+	args := jt.Args()
+	require.Equal(t, 3, len(args))
+	require.Equal(t, "name", args[0].Name)
+	require.Equal(t, -1, args[0].Index)
+	require.Equal(t, "/name", args[0].Pointer)
+	require.Equal(t, "", args[1].Name)
+	require.Equal(t, 0, args[1].Index)
+	require.Equal(t, "/values/0", args[1].Pointer)
+	require.Equal(t, "/values/1", args[2].Pointer)
+}
+
+func TestArgContextString(t *testing.T) {
+	require.Equal(t, "unknown", ArgContextUnknown.String())
+	require.Equal(t, "value", ArgContextValue.String())
+	require.Equal(t, "members", ArgContextMembers.String())
+	require.Equal(t, "key", ArgContextKey.String())
+	require.Equal(t, "in-string", ArgContextInString.String())
+}
@@ -2,6 +2,7 @@ package jsont
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -21,21 +22,56 @@ type Template interface {
 	//
 	// Each arg must be able to JSON Marshall
 	Data(args ...interface{}) ([]byte, error)
+	// RenderContext produces a JSON []byte data from the template using the specified args - in the same way as Data
+	//
+	// Any arg can be a lazy value - a func(ctx context.Context) (interface{}, error) - which is called (with the
+	// context) to obtain the actual arg value
+	//
+	// Rendering stops (and returns the context error) if the context is cancelled
+	RenderContext(ctx context.Context, args ...interface{}) ([]byte, error)
 	// ExpectedArgs returns the expected number of args (that String() and Data() expects)
 	ExpectedArgs() int
+	// Args returns information about each arg marker in the template (in template order) - including
+	// the position of the marker and the JSON Pointer of where the arg value lands in the output
+	Args() []ArgInfo
+	// Schema generates a JSON Schema (draft 2020-12) describing the output of the template
+	//
+	// Fixed parts of the template are described as const values and arg markers are described as
+	// untyped (i.e. '{}') schemas
+	//
+	// An error is returned if the structure of the template could not be determined
+	Schema() (map[string]interface{}, error)
 	// NewWith creates a new template with the args supplied being resolved in the new template
 	NewWith(args ...interface{}) (Template, error)
+	// Bind binds the template with the specified args - the bound template can then be used as an arg value for
+	// another template (and is rendered directly into the output of the other template)
+	Bind(args ...interface{}) *BoundTemplate
+	// Options returns a copy of the template with the specified options applied - the template itself is unchanged (templates
+	// are never changed in place, so can be safely shared and rendered concurrently)
+	//
+	// Note: unlike using options with NewTemplate and MustCompileTemplate, this method
+	// does not panic or error if any of the options are not applicable to this type
 	Options(options ...Option) Template
+	// Clone creates a copy of the template - the copy shares the parsed template and copies only the
+	// configuration (e.g. strictness)
+	Clone() Template
+	// With creates a copy of the template with the specified options applied - the copy shares the parsed template
+	// (so the template string is not re-parsed)
+	//
+	// Unlike Options, an error is returned if any of the options cannot be applied
+	With(options ...Option) (Template, error)
 }
 
 type jsonTemplate struct {
-	argsCount int
-	tokens    tokens
-	fixedLens int
-	strict    bool
-	checkReqd bool
-	// used only during parsing...
-	lastTokenStart int
+	argsCount     int
+	tokens        tokens
+	argInfos      []ArgInfo
+	fixedLens     int
+	strict        bool
+	checkReqd     bool
+	collectErrors bool
+	schema        *jsonSchema
+	validation    jsonValidation
 }
 
 // NewTemplate creates a new JSON template from a template string
@@ -44,17 +80,28 @@ type jsonTemplate struct {
 //
 // To escape a '?' in the template, use '??'
 //
+// Arg positions can also be indexed - e.g. '?1', '?2' - so that the same arg can be used more
+// than once in the template (indexes are 1 based, every index up to the highest must be used and indexed
+// arg positions cannot be mixed with plain '?' arg positions)
+//
 // Example:
 //   jt, _ := NewTemplate(`{"foo":?,"bar":?,"baz":"??","qux":?}`)
 //   println(jt.String("aaa", "bbb", 1.2))
 // would produce:
 //   {"foo":"aaa","bar":"bbb","baz":"?","qux":1.2}
+// or, using indexed arg positions:
+//   jt, _ := NewTemplate(`{"id":?1,"parent":{"id":?1},"name":?2}`)
+//   println(jt.String("a1", "aaa"))
+// would produce:
+//   {"id":"a1","parent":{"id":"a1"},"name":"aaa"}
 func NewTemplate(template string, options ...Option) (Template, error) {
 	result := &jsonTemplate{
 		tokens: make(tokens, 0),
 		strict: true,
 	}
-	result.parse(template)
+	if err := result.parse(template); err != nil {
+		return nil, err
+	}
 	if err := result.applyOptions(options, false); err != nil {
 		return nil, err
 	}
@@ -74,13 +121,34 @@ func MustCompileTemplate(template string, options ...Option) Template {
 	}
 }
 
-// Options applies the specified options to the template
-//
-// Note: unlike using options with NewTemplate and MustCompileTemplate, this method
-// does not panic or error if any of the options are not applicable to this type
+// Options returns a copy of the template with the options applied (see Template.Options)
 func (t *jsonTemplate) Options(options ...Option) Template {
-	_ = t.applyOptions(options, true)
-	return t
+	result := t.clone()
+	_ = result.applyOptions(options, true)
+	return result
+}
+
+// Clone creates a copy of the template (see Template.Clone)
+func (t *jsonTemplate) Clone() Template {
+	return t.clone()
+}
+
+// With creates a copy of the template with the options applied (see Template.With)
+func (t *jsonTemplate) With(options ...Option) (Template, error) {
+	result := t.clone()
+	if err := result.applyOptions(options, false); err != nil {
+		return nil, err
+	}
+	if err := result.check(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// clone copies the template - the tokens are immutable and are shared
+func (t *jsonTemplate) clone() *jsonTemplate {
+	result := *t
+	return &result
 }
 
 func (t *jsonTemplate) applyOptions(options []Option, ignoreErrs bool) error {
@@ -100,25 +168,22 @@ func (t *jsonTemplate) applyOptions(options []Option, ignoreErrs bool) error {
 //
 // Each arg must be able to JSON Marshall
 func (t *jsonTemplate) String(args ...interface{}) (string, error) {
-	if err := t.checkArgs(args); err != nil {
-		return "null", err
-	}
-	argsData, argsLen, err := t.getArgsData(args)
-	if err != nil {
+	validator := newOutputValidator(t.validation)
+	var builder strings.Builder
+	if err := t.renderTo(context.Background(), &builder, args, validator); err != nil {
 		return "null", err
 	}
-	var builder strings.Builder
-	builder.Grow(t.fixedLens + argsLen)
-	arg := 0
-	for _, tkn := range t.tokens {
-		if tkn.fixed {
-			builder.Write(tkn.fixedValue)
-		} else {
-			builder.Write(argsData[arg])
-			arg++
+	result := builder.String()
+	if validator != nil || t.schema != nil {
+		// only copied to data when the output is validated...
+		data := []byte(result)
+		if err := validator.validate(data); err != nil {
+			return "null", err
+		} else if err = t.schema.validate(data); err != nil {
+			return "null", err
 		}
 	}
-	return builder.String(), nil
+	return result, nil
 }
 
 // Data produces a JSON []byte data from the template using the specified args
@@ -127,45 +192,45 @@ func (t *jsonTemplate) String(args ...interface{}) (string, error) {
 //
 // Each arg must be able to JSON Marshall
 func (t *jsonTemplate) Data(args ...interface{}) ([]byte, error) {
-	if err := t.checkArgs(args); err != nil {
-		return nil, err
+	return t.RenderContext(context.Background(), args...)
+}
+
+// RenderContext produces a JSON []byte data from the template using the context (see Template.RenderContext)
+func (t *jsonTemplate) RenderContext(ctx context.Context, args ...interface{}) ([]byte, error) {
+	data, err := t.data(ctx, args, newOutputValidator(t.validation))
+	if err == nil {
+		err = t.schema.validate(data)
 	}
-	argsData, argsLen, err := t.getArgsData(args)
 	if err != nil {
 		return nil, err
 	}
+	return data, nil
+}
+
+// data produces the JSON data from the template (without validating against any schema) - the output is
+// validated only if a validator is supplied
+func (t *jsonTemplate) data(ctx context.Context, args []interface{}, validator *outputValidator) ([]byte, error) {
 	var buffer bytes.Buffer
-	buffer.Grow(t.fixedLens + argsLen)
-	arg := 0
-	for _, tkn := range t.tokens {
-		if tkn.fixed {
-			buffer.Write(tkn.fixedValue)
-		} else {
-			buffer.Write(argsData[arg])
-			arg++
-		}
+	if err := t.renderTo(ctx, &buffer, args, validator); err != nil {
+		return nil, err
+	} else if err = validator.validate(buffer.Bytes()); err != nil {
+		return nil, err
 	}
 	return buffer.Bytes(), nil
 }
 
-func (t *jsonTemplate) getArgsData(args []interface{}) (argsData [][]byte, argsLen int, err error) {
-	argsData = make([][]byte, t.argsCount)
-	argsLen = 0
-	l := len(args)
-	for i := 0; i < l; i++ {
-		if ad, e := argValueToData(args[i]); e == nil {
-			argsData[i] = ad
-			argsLen += len(ad)
-		} else {
-			err = e
-			break
-		}
+// renderTo renders the template (using the specified args) into the writer
+func (t *jsonTemplate) renderTo(ctx context.Context, w renderWriter, args []interface{}, validator *outputValidator) error {
+	if err := checkArgsCount(t.strict, t.argsCount, args); err != nil {
+		return err
 	}
-	for i := l; i < t.argsCount; i++ {
-		argsData[i] = nullData
-		argsLen += nullDataLen
+	collector := newArgErrorsCollector(t.collectErrors)
+	argsData, writers, argsLen, err := getArgsData(ctx, args, t.argsCount, t.tokens, t.validation, collector)
+	if err != nil {
+		return err
 	}
-	return
+	w.Grow(t.fixedLens + argsLen)
+	return renderTokens(ctx, w, t.tokens, argsData, writers, nil, nil, validator, collector)
 }
 
 // ExpectedArgs returns the expected number of args (that String() and Data() expects)
@@ -173,89 +238,87 @@ func (t *jsonTemplate) ExpectedArgs() int {
 	return t.argsCount
 }
 
-func (t *jsonTemplate) checkArgs(args []interface{}) error {
-	if t.strict && len(args) != t.argsCount {
-		return fmt.Errorf("expected %d args but supplied %d args", t.argsCount, len(args))
-	}
-	return nil
+// Args returns information about each arg marker in the template (see Template.Args)
+func (t *jsonTemplate) Args() []ArgInfo {
+	return copyArgInfos(t.argInfos)
+}
+
+// Schema generates a JSON Schema describing the output of the template (see Template.Schema)
+func (t *jsonTemplate) Schema() (map[string]interface{}, error) {
+	return t.tokens.schema(positionalArgSchema)
 }
 
 // NewWith creates a new template with the args supplied being resolved in the new template
 func (t *jsonTemplate) NewWith(args ...interface{}) (Template, error) {
 	lArgs := len(args)
 	if lArgs > t.argsCount {
-		return nil, fmt.Errorf("too many args supplied (%d) - expected maximum of %d", lArgs, t.argsCount)
+		return nil, &ArgCountError{
+			Expected: t.argsCount,
+			Supplied: lArgs,
+			message:  fmt.Sprintf("too many args supplied (%d) - expected maximum of %d", lArgs, t.argsCount),
+		}
 	}
 	result := &jsonTemplate{
-		argsCount: t.argsCount - len(args),
-		tokens:    tokens{},
-		fixedLens: t.fixedLens,
-		strict:    t.strict,
+		argsCount:     t.argsCount - len(args),
+		tokens:        tokens{},
+		fixedLens:     t.fixedLens,
+		strict:        t.strict,
+		collectErrors: t.collectErrors,
+		schema:        t.schema,
+		validation:    t.validation,
+	}
+	argsData, writers, _, err := getArgsData(context.Background(), args, lArgs, t.tokens, t.validation, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i, dw := range writers {
+		if argsData[i], err = dw.JSONTData(); err != nil {
+			return nil, &ArgMarshalError{
+				ArgIndex: i,
+				Position: t.tokens.argIndexPosition(i),
+				Err:      err,
+			}
+		}
 	}
-	onArg := 0
 	for _, tkn := range t.tokens {
-		if tkn.fixed || onArg >= lArgs {
+		if tkn.fixed {
 			result.tokens = append(result.tokens, tkn)
-		} else if aData, err := argValueToData(args[onArg]); err != nil {
-			return nil, err
-		} else {
+		} else if tkn.argIndex < lArgs {
 			result.tokens = append(result.tokens, jsonTemplateToken{
 				fixed:      true,
-				fixedValue: aData,
+				fixedValue: argsData[tkn.argIndex],
 			})
-			result.fixedLens += len(aData)
-			onArg++
+			result.fixedLens += len(argsData[tkn.argIndex])
+		} else {
+			tkn.argIndex -= lArgs
+			result.tokens = append(result.tokens, tkn)
 		}
 	}
 	result.tokens = result.tokens.joinContiguousFixed()
+	result.argInfos = result.tokens.argInfos()
 	return result, nil
 }
 
-func (t *jsonTemplate) parse(template string) {
-	data := []byte(template)
-	l := len(data)
-	maxI := l - 1
-	t.lastTokenStart = 0
-	t.fixedLens = 0
-	t.argsCount = 0
-	for i := 0; i < l; i++ {
-		if data[i] == '?' {
-			if i < maxI && data[i+1] == '?' {
-				t.parseAddFixedToken(i+1, data)
-				i++
-				t.lastTokenStart = i + 1
-			} else {
-				t.parseAddArgToken(i, data)
-			}
-		}
-	}
-	t.parseAddFixedToken(l, data)
-	t.tokens = t.tokens.joinContiguousFixed()
-}
-
-func (t *jsonTemplate) parseAddFixedToken(i int, data []byte) {
-	if i > t.lastTokenStart {
-		t.tokens = append(t.tokens, jsonTemplateToken{
-			fixed:      true,
-			fixedValue: data[t.lastTokenStart:i],
-		})
-		t.fixedLens += i - t.lastTokenStart
+func (t *jsonTemplate) parse(template string) error {
+	p, err := parseTemplate(template, true, false)
+	if err != nil {
+		return err
 	}
-}
-
-func (t *jsonTemplate) parseAddArgToken(i int, data []byte) {
-	t.parseAddFixedToken(i, data)
-	t.tokens = append(t.tokens, jsonTemplateToken{})
-	t.lastTokenStart = i + 1
-	t.argsCount++
+	t.tokens = p.tokens.joinContiguousFixed()
+	t.argInfos = t.tokens.argInfos()
+	t.fixedLens = p.fixedLens
+	t.argsCount = p.argsCount
+	return nil
 }
 
 func (t *jsonTemplate) check() (err error) {
 	if t.checkReqd {
 		tArgs := make([]interface{}, t.argsCount)
-		tData, _ := t.Data(tArgs...)
+		tData, _ := t.data(context.Background(), tArgs, nil)
 		var v interface{}
-		err = json.Unmarshal(tData, &v)
+		if err = json.Unmarshal(tData, &v); err != nil {
+			err = t.tokens.checkError(err)
+		}
 	}
 	return
 }
@@ -1,6 +1,8 @@
 package jsont
 
 import (
+	"context"
+	"errors"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -89,6 +91,11 @@ func TestTemplateNonStrict(t *testing.T) {
 	data, err := jt.Data(1.1)
 	require.NoError(t, err)
 	require.Equal(t, str, string(data[:]))
+
+	// extra args are ignored...
+	str, err = jt.String(1, 2, 3, 4, func() {})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":1,"bar":2,"baz":"?","qux":3}`, str)
 }
 
 func TestTemplate_NewWith(t *testing.T) {
@@ -129,6 +136,181 @@ func TestTemplate_Options(t *testing.T) {
 	require.NotNil(t, jt)
 
 	require.True(t, (jt.(*jsonTemplate)).strict)
-	jt.Options(OptionNonStrict)
+	jt = jt.Options(OptionNonStrict)
 	require.False(t, (jt.(*jsonTemplate)).strict)
 }
+
+func TestTemplateIndexedArgs(t *testing.T) {
+	jt, err := NewTemplate(`{"id":?1,"parent":{"id":?1},"name":?2,"other":?1}`)
+	require.NoError(t, err)
+	require.Equal(t, 2, jt.ExpectedArgs())
+	str, err := jt.String("a1", "aaa")
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"a1","parent":{"id":"a1"},"name":"aaa","other":"a1"}`, str)
+	data, err := jt.Data("a1", "aaa")
+	require.NoError(t, err)
+	require.Equal(t, str, string(data[:]))
+
+	_, err = jt.String("a1")
+	require.Error(t, err)
+	require.Equal(t, "expected 2 args but supplied 1 args", err.Error())
+
+	jt, err = NewTemplate(`[?2,?1]`, OptionNonStrict)
+	require.NoError(t, err)
+	require.Equal(t, 2, jt.ExpectedArgs())
+	str, err = jt.String(1)
+	require.NoError(t, err)
+	require.Equal(t, `[null,1]`, str)
+}
+
+type marshalCounter struct {
+	count int
+}
+
+func (m *marshalCounter) MarshalJSON() ([]byte, error) {
+	m.count++
+	return []byte(`"counted"`), nil
+}
+
+func TestTemplateIndexedArgsMarshalledOnce(t *testing.T) {
+	jt, err := NewTemplate(`[?1,?1,?1,?2]`)
+	require.NoError(t, err)
+	mc := &marshalCounter{}
+	str, err := jt.String(mc, mc)
+	require.NoError(t, err)
+	require.Equal(t, `["counted","counted","counted","counted"]`, str)
+	require.Equal(t, 2, mc.count)
+}
+
+func TestTemplateIndexedArgsErrors(t *testing.T) {
+	_, err := NewTemplate(`{"foo":?0}`)
+	require.Error(t, err)
+	require.Equal(t, "invalid arg index '0' at position 7", err.Error())
+
+	_, err = NewTemplate(`{"foo":?99999999999999999999}`)
+	require.Error(t, err)
+
+	// plain and indexed args cannot be mixed...
+	_, err = NewTemplate(`[?1,?]`)
+	require.Error(t, err)
+	require.Equal(t, "cannot mix '?' and indexed '?n' positional args at position 4", err.Error())
+	_, err = NewTemplate(`[?,?2]`)
+	require.Error(t, err)
+	require.Equal(t, "cannot mix '?' and indexed '?n' positional args at position 3", err.Error())
+
+	// indices cannot be skipped...
+	_, err = NewTemplate(`[?3]`)
+	require.Error(t, err)
+	require.Equal(t, "arg index '3' at position 1 skips unused arg index '1'", err.Error())
+	_, err = NewTemplate(`[?1,?1,?3,?2,?5]`)
+	require.Error(t, err)
+	require.Equal(t, "arg index '5' at position 13 skips unused arg index '4'", err.Error())
+	var tse *TemplateSyntaxError
+	require.True(t, errors.As(err, &tse))
+	require.Equal(t, Position{Offset: 13, Line: 1, Column: 14}, tse.Position)
+}
+
+func TestTemplate_NewWithIndexedArgs(t *testing.T) {
+	org, err := NewTemplate(`{"foo":?2,"bar":?1,"baz":?2,"qux":?3}`)
+	require.NoError(t, err)
+
+	jt, err := org.NewWith("aaa", "bbb")
+	require.NoError(t, err)
+	require.Equal(t, 1, jt.ExpectedArgs())
+	str, err := jt.String("ccc")
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"bbb","bar":"aaa","baz":"bbb","qux":"ccc"}`, str)
+
+	jt, err = org.NewWith("aaa")
+	require.NoError(t, err)
+	require.Equal(t, 2, jt.ExpectedArgs())
+	str, err = jt.String("bbb", "ccc")
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"bbb","bar":"aaa","baz":"bbb","qux":"ccc"}`, str)
+}
+
+type testCtxKey struct{}
+
+func TestTemplateRenderContext(t *testing.T) {
+	jt, err := NewTemplate(`{"foo":?,"bar":?}`)
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), testCtxKey{}, "from context")
+	lazy := func(ctx context.Context) (interface{}, error) {
+		return ctx.Value(testCtxKey{}), nil
+	}
+	data, err := jt.RenderContext(ctx, lazy, 1)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"from context","bar":1}`, string(data))
+
+	// lazy values are also resolved by String and Data...
+	str, err := jt.String(lazy, 2)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":null,"bar":2}`, str)
+
+	failing := func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("fooey")
+	}
+	_, err = jt.RenderContext(ctx, 1, failing)
+	require.Error(t, err)
+	require.Equal(t, "arg[1] at line 1, column 16: fooey", err.Error())
+
+	cctx, cancel := context.WithCancel(context.Background())
+	called := false
+	cancelling := func(ctx context.Context) (interface{}, error) {
+		cancel()
+		return "a", nil
+	}
+	notCalled := func(ctx context.Context) (interface{}, error) {
+		called = true
+		return "b", nil
+	}
+	data, err = jt.RenderContext(cctx, cancelling, notCalled)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Nil(t, data)
+	require.False(t, called)
+}
+
+func TestTemplateCopyOnWrite(t *testing.T) {
+	jt := MustCompileTemplate(`{"a":?,"b":?}`)
+	jt2 := jt.Options(OptionNonStrict)
+	require.NotSame(t, jt, jt2)
+	require.Equal(t, &(jt.(*jsonTemplate)).tokens[0], &(jt2.(*jsonTemplate)).tokens[0])
+
+	_, err := jt.String(1)
+	require.Error(t, err)
+	str, err := jt2.String(1)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":null}`, str)
+}
+
+func TestTemplateCloneAndWith(t *testing.T) {
+	jt := MustCompileTemplate(`{"a":?,"b":?}`)
+	clone := jt.Clone()
+	require.NotSame(t, jt, clone)
+	require.Equal(t, &(jt.(*jsonTemplate)).tokens[0], &(clone.(*jsonTemplate)).tokens[0])
+	clone = clone.Options(OptionNonStrict)
+	_, err := jt.String(1)
+	require.Error(t, err)
+	_, err = clone.String(1)
+	require.NoError(t, err)
+
+	jt2, err := jt.With(OptionNonStrict, OptionCollectErrors)
+	require.NoError(t, err)
+	require.NotSame(t, jt, jt2)
+	require.Equal(t, &(jt.(*jsonTemplate)).tokens[0], &(jt2.(*jsonTemplate)).tokens[0])
+	require.False(t, (jt.(*jsonTemplate)).collectErrors)
+	require.True(t, (jt2.(*jsonTemplate)).collectErrors)
+	str, err := jt2.String(1)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":null}`, str)
+
+	_, err = jt.With(OptionDefaultArgValue("a", 1))
+	require.Error(t, err)
+
+	// checked...
+	jt = MustCompileTemplate(`{"a":?,}`)
+	_, err = jt.With(OptionChecked)
+	require.Error(t, err)
+	require.Equal(t, "invalid JSON template at line 1, column 8: invalid character '}' looking for beginning of object key string", err.Error())
+}
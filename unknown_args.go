@@ -0,0 +1,136 @@
+package jsont
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// unknownArgsKey is the key used for unknown args errors when collecting errors (it cannot clash with any arg name)
+const unknownArgsKey = "?unknown"
+
+// checkUnknownArgs checks for supplied args that are not expected by the template (when OptionRejectUnknownArgs is used)
+//
+// Only args supplied as a map (i.e. to String/Data or as MapArgs) can be checked
+func (t *jsonNamedTemplate) checkUnknownArgs(args ArgResolver) error {
+	if !t.rejectUnknown {
+		return nil
+	}
+	m, ok := args.(MapArgs)
+	if !ok {
+		return nil
+	}
+	var unknown []string
+	for k := range m {
+		if !t.isKnownArgName(k) {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	result := &UnknownArgsError{
+		ArgNames:    unknown,
+		Suggestions: map[string]string{},
+	}
+	for _, name := range unknown {
+		if suggestion := t.suggestArgName(name); suggestion != "" {
+			result.Suggestions[name] = suggestion
+		}
+	}
+	return result
+}
+
+// isKnownArgName determines whether a supplied arg name is expected by the template - either as a
+// named arg or as the first segment(s) of a dotted path named arg
+func (t *jsonNamedTemplate) isKnownArgName(name string) bool {
+	if t.argNames[name] {
+		return true
+	}
+	prefix := name + "."
+	for argName := range t.argNames {
+		if strings.HasPrefix(argName, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestArgName returns the closest expected arg name (by edit distance) - or an empty string
+// if there is no sufficiently close expected arg name
+func (t *jsonNamedTemplate) suggestArgName(name string) string {
+	best := ""
+	bestDist := len(name)/3 + 2
+	for argName := range t.argNames {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(argName)); d < bestDist || (d == bestDist && best != "" && argName < best) {
+			best = argName
+			bestDist = d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// UnknownArgsError is the error returned (when using OptionRejectUnknownArgs) when the args supplied
+// contain names that are not expected by the template
+//
+// errors.Is(err, ErrUnknownArg) returns true for this error
+type UnknownArgsError struct {
+	// ArgNames is the (sorted) list of unknown arg names
+	ArgNames []string
+	// Suggestions is a map of unknown arg names to the closest expected arg name (where there is one)
+	Suggestions map[string]string
+}
+
+func (e *UnknownArgsError) Error() string {
+	var builder strings.Builder
+	if len(e.ArgNames) == 1 {
+		builder.WriteString("unknown named arg ")
+	} else {
+		builder.WriteString("unknown named args ")
+	}
+	for i, name := range e.ArgNames {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(fmt.Sprintf("'%s'", name))
+		if suggestion, ok := e.Suggestions[name]; ok {
+			builder.WriteString(fmt.Sprintf(" (did you mean '%s'?)", suggestion))
+		}
+	}
+	return builder.String()
+}
+
+// Is reports whether the target is ErrUnknownArg
+func (e *UnknownArgsError) Is(target error) bool {
+	return target == ErrUnknownArg
+}
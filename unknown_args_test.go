@@ -0,0 +1,95 @@
+package jsont
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNamedTemplateRejectUnknownArgs(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"userName":?userName,"city":?user.address.city,"email":?email?:altEmail}`, OptionNonStrict)
+	require.NoError(t, err)
+
+	str, err := jt.String(map[string]interface{}{"usrName": "x"})
+	require.NoError(t, err)
+	require.Equal(t, `{"userName":null,"city":null,"email":null}`, str)
+
+	jt = jt.Options(OptionRejectUnknownArgs)
+	_, err = jt.String(map[string]interface{}{"usrName": "x"})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrUnknownArg))
+	require.False(t, errors.Is(err, ErrMissingArg))
+	var uae *UnknownArgsError
+	require.True(t, errors.As(err, &uae))
+	require.Equal(t, []string{"usrName"}, uae.ArgNames)
+	require.Equal(t, "userName", uae.Suggestions["usrName"])
+	require.Equal(t, "unknown named arg 'usrName' (did you mean 'userName'?)", err.Error())
+
+	_, err = jt.Data(map[string]interface{}{"zzzzzz": 1, "Email": "x", "userName": "x", "user": nil, "altEmail": nil})
+	require.Error(t, err)
+	require.Equal(t, "unknown named args 'Email' (did you mean 'email'?), 'zzzzzz'", err.Error())
+
+	str, err = jt.String(map[string]interface{}{"userName": "x", "user": nil, "user.address": nil, "altEmail": "y"})
+	require.NoError(t, err)
+	require.Equal(t, `{"userName":"x","city":null,"email":"y"}`, str)
+
+	// only map args are checked...
+	str, err = jt.RenderString(ChainArgs(MapArgs{"usrName": "x"}))
+	require.NoError(t, err)
+	require.Equal(t, `{"userName":null,"city":null,"email":null}`, str)
+	_, err = jt.RenderString(MapArgs{"usrName": "x"})
+	require.Error(t, err)
+
+	jt, err = jt.NewWith(map[string]interface{}{"userName": "x"})
+	require.NoError(t, err)
+	_, err = jt.String(map[string]interface{}{"userName": "x"})
+	require.Error(t, err)
+	require.Equal(t, "unknown named arg 'userName'", err.Error())
+
+	jt = jt.Options(OptionAllowUnknownArgs)
+	_, err = jt.String(map[string]interface{}{"userName": "x"})
+	require.NoError(t, err)
+}
+
+func TestNamedTemplateRejectUnknownArgsCollected(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"foo":?foo}`, OptionRejectUnknownArgs, OptionCollectErrors)
+	require.NoError(t, err)
+
+	_, err = jt.String(map[string]interface{}{"fooo": 1})
+	var aes *ArgErrors
+	require.True(t, errors.As(err, &aes))
+	require.Equal(t, 2, len(aes.Errors))
+	require.True(t, errors.Is(err, ErrUnknownArg))
+	require.True(t, errors.Is(err, ErrMissingArg))
+	_, err = jt.Data(map[string]interface{}{"fooo": 1})
+	require.True(t, errors.As(err, &aes))
+}
+
+func TestMixedTemplateRejectUnknownArgs(t *testing.T) {
+	jt, err := NewMixedTemplate(`{"foo":?foo,"bar":?}`, OptionRejectUnknownArgs)
+	require.NoError(t, err)
+	_, err = jt.String(map[string]interface{}{"foo": 1, "bar": 2}, 1)
+	require.True(t, errors.Is(err, ErrUnknownArg))
+	_, err = jt.Data(map[string]interface{}{"foo": 1, "bar": 2}, 1)
+	require.True(t, errors.Is(err, ErrUnknownArg))
+
+	jt = jt.Options(OptionCollectErrors)
+	_, err = jt.String(map[string]interface{}{"bar": 2}, func() {})
+	var aes *ArgErrors
+	require.True(t, errors.As(err, &aes))
+	require.Equal(t, 3, len(aes.Errors))
+	_, err = jt.Data(map[string]interface{}{"bar": 2}, func() {})
+	require.True(t, errors.As(err, &aes))
+
+	_, err = NewTemplate(`[?]`, OptionRejectUnknownArgs)
+	require.Error(t, err)
+}
+
+func TestEditDistance(t *testing.T) {
+	require.Equal(t, 0, editDistance("", ""))
+	require.Equal(t, 3, editDistance("abc", ""))
+	require.Equal(t, 3, editDistance("", "abc"))
+	require.Equal(t, 1, editDistance("usrName", "userName"))
+	require.Equal(t, 3, editDistance("kitten", "sitting"))
+	require.Equal(t, 1, editDistance("héllo", "hello"))
+}
@@ -0,0 +1,101 @@
+package jsont
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonValidation is the render-time JSON validation performed by a template (see OptionValidateOutput and OptionValidateRawArgs)
+type jsonValidation int
+
+const (
+	validateNone jsonValidation = iota
+	validateRawArgs
+	validateOutput
+)
+
+// checkRawArgData checks that the data for raw arg values ([]byte, json.RawMessage, *NameValuePair, *NameValuePairs
+// and other DataProvider values) is valid JSON
+func checkRawArgData(v interface{}, data []byte) error {
+	switch v.(type) {
+	case []byte, json.RawMessage:
+		return checkValidJSON(data, "raw arg value")
+	case *NameValuePair, *NameValuePairs:
+		if len(data) > 0 {
+			wrapped := make([]byte, 0, len(data)+2)
+			wrapped = append(wrapped, '{')
+			wrapped = append(wrapped, data...)
+			wrapped = append(wrapped, '}')
+			return checkValidJSON(wrapped, "name value")
+		}
+	case DataProvider:
+		return checkValidJSON(data, "data provider value")
+	}
+	return nil
+}
+
+func checkValidJSON(data []byte, what string) error {
+	if json.Valid(data) {
+		return nil
+	}
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	return fmt.Errorf("%w %s: %s", ErrInvalidJSON, what, err.Error())
+}
+
+// outputValidator validates rendered output is valid JSON (when OptionValidateOutput is used) - a nil
+// validator indicates output is not being validated
+//
+// During rendering, the start of each rendered arg value is recorded - so that an error can report the
+// arg that (most likely) caused the invalid output
+type outputValidator struct {
+	spans []argSpan
+}
+
+type argSpan struct {
+	tkn   jsonTemplateToken
+	start int
+}
+
+func newOutputValidator(validation jsonValidation) *outputValidator {
+	if validation == validateOutput {
+		return &outputValidator{}
+	}
+	return nil
+}
+
+// add records the start offset (in the rendered output) of an arg value
+func (v *outputValidator) add(tkn jsonTemplateToken, start int) {
+	if v != nil {
+		v.spans = append(v.spans, argSpan{tkn: tkn, start: start})
+	}
+}
+
+func (v *outputValidator) validate(data []byte) error {
+	if v == nil || json.Valid(data) {
+		return nil
+	}
+	var jv interface{}
+	err := json.Unmarshal(data, &jv)
+	offset := len(data)
+	if se, ok := err.(*json.SyntaxError); ok && se.Offset > 0 {
+		offset = int(se.Offset) - 1
+	}
+	result := &InvalidOutputError{
+		ArgIndex: -1,
+		Offset:   offset,
+		Err:      err,
+	}
+	for i := len(v.spans) - 1; i >= 0; i-- {
+		if span := v.spans[i]; span.start <= offset {
+			result.ArgName = span.tkn.argName
+			if span.tkn.argName == "" {
+				result.ArgIndex = span.tkn.argIndex
+			}
+			result.Position = span.tkn.pos
+			result.arg = true
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,108 @@
+package jsont
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTemplateOptionValidateOutput(t *testing.T) {
+	jt, err := NewTemplate(`{"a":?,"b":?}`, OptionValidateOutput)
+	require.NoError(t, err)
+
+	str, err := jt.String([]byte(`{"x":1}`), json.RawMessage(`[1]`))
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"x":1},"b":[1]}`, str)
+
+	str, err = jt.String(1, []byte(`1,`))
+	require.Error(t, err)
+	require.Equal(t, "null", str)
+	require.True(t, errors.Is(err, ErrInvalidJSON))
+	var ioe *InvalidOutputError
+	require.True(t, errors.As(err, &ioe))
+	require.Equal(t, 1, ioe.ArgIndex)
+	require.Equal(t, 13, ioe.Offset)
+	require.Equal(t, Position{Offset: 11, Line: 1, Column: 12}, ioe.Position)
+	require.Equal(t, "invalid JSON output at offset 13 (after arg[1] at line 1, column 12): invalid character '}' looking for beginning of object key string", err.Error())
+
+	data, err := jt.Data([]byte(`"abc`), 1)
+	require.Error(t, err)
+	require.Nil(t, data)
+	require.Equal(t, "invalid JSON output at offset 11 (after arg[0] at line 1, column 6): invalid character 'b' after object key:value pair", err.Error())
+
+	data, err = jt.Data([]byte(`{`), 1)
+	require.Error(t, err)
+	require.Nil(t, data)
+	require.True(t, errors.As(err, &ioe))
+	require.Equal(t, 0, ioe.ArgIndex)
+
+	// template itself invalid (and not checked)...
+	jt, err = NewTemplate(`{"a":?,}`, OptionValidateOutput)
+	require.NoError(t, err)
+	_, err = jt.String(1)
+	require.Error(t, err)
+	require.Equal(t, "invalid JSON output at offset 7 (after arg[0] at line 1, column 6): invalid character '}' looking for beginning of object key string", err.Error())
+	jt, err = NewTemplate(`,{"a":?}`, OptionValidateOutput)
+	require.NoError(t, err)
+	_, err = jt.String(1)
+	require.Error(t, err)
+	require.Equal(t, "invalid JSON output at offset 0: invalid character ',' looking for beginning of value", err.Error())
+
+	// checked template still reports syntax errors...
+	_, err = NewTemplate(`{"a":?,}`, OptionValidateOutput, OptionChecked)
+	require.Error(t, err)
+	require.Equal(t, "invalid JSON template at line 1, column 8: invalid character '}' looking for beginning of object key string", err.Error())
+}
+
+func TestNamedTemplateOptionValidateOutput(t *testing.T) {
+	jt, err := NewNamedTemplate(`{"a":?a,?b}`, OptionValidateOutput)
+	require.NoError(t, err)
+	str, err := jt.String(map[string]interface{}{"a": 1, "b": NameValues(NameValue("c", 2))})
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"c":2}`, str)
+
+	_, err = jt.String(map[string]interface{}{"a": 1, "b": NameValues()})
+	require.Error(t, err)
+	require.Equal(t, "invalid JSON output at offset 7 (after named arg 'b' at line 1, column 9): invalid character '}' looking for beginning of object key string", err.Error())
+	_, err = jt.Data(map[string]interface{}{"a": json.RawMessage(`x`), "b": NameValues(NameValue("c", 2))})
+	require.Error(t, err)
+	require.Equal(t, "invalid JSON output at offset 5 (after named arg 'a' at line 1, column 6): invalid character 'x' looking for beginning of value", err.Error())
+}
+
+func TestMixedTemplateOptionValidateOutput(t *testing.T) {
+	jt, err := NewMixedTemplate(`[?a,?]`, OptionValidateOutput)
+	require.NoError(t, err)
+	_, err = jt.String(map[string]interface{}{"a": 1}, []byte(`2`))
+	require.NoError(t, err)
+	_, err = jt.Data(map[string]interface{}{"a": 1}, []byte(`2]`))
+	require.Error(t, err)
+	require.Equal(t, "invalid JSON output at offset 5 (after arg[0] at line 1, column 5): invalid character ']' after top-level value", err.Error())
+}
+
+func TestOptionValidateRawArgs(t *testing.T) {
+	jt, err := NewTemplate(`{"a":?,"b":?}`, OptionValidateRawArgs)
+	require.NoError(t, err)
+	_, err = jt.String(json.RawMessage(`{}`), NameValue("x", 1))
+	require.NoError(t, err)
+	_, err = jt.String(1, []byte(`1,`))
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrInvalidJSON))
+	var ame *ArgMarshalError
+	require.True(t, errors.As(err, &ame))
+	require.Equal(t, 1, ame.ArgIndex)
+	require.Equal(t, "arg[1] at line 1, column 12: invalid JSON raw arg value: invalid character ',' after top-level value", err.Error())
+
+	njt, err := NewNamedTemplate(`{?nv}`, OptionValidateRawArgs)
+	require.NoError(t, err)
+	_, err = njt.String(map[string]interface{}{"nv": NameValues(NameValue("x", []byte(`"unterminated`)))})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrInvalidJSON))
+	require.Equal(t, "named arg 'nv' at line 1, column 2: invalid JSON name value: unexpected end of JSON input", err.Error())
+
+	mjt, err := NewMixedTemplate(`[?a,?]`, OptionValidateRawArgs)
+	require.NoError(t, err)
+	_, err = mjt.String(map[string]interface{}{"a": []byte(`[`)}, 1)
+	require.Error(t, err)
+	require.Equal(t, "named arg 'a' at line 1, column 2: invalid JSON raw arg value: unexpected end of JSON input", err.Error())
+}